@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"os"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/middleware"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/service"
+	"salesforce-sse-worker/internal/service/outbound"
+	"sort"
+	"strings"
+	"time"
+)
+
+type dlqMessage struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	Key       string            `json:"key,omitempty"`
+	Value     string            `json:"value"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+func generateToken(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("token generate", flag.ExitOnError)
+	partition := flags.Int("partition", -1, "partition to regenerate, all partitions when omitted")
+	_ = flags.Parse(args)
+
+	return invoke(func(queueConfig configs.QueueConfig, queueAdmin library.QueueAdmin, conversationService service.ConversationService) error {
+		if *partition >= 0 {
+			if err := conversationService.RegenerateToken(ctx, *partition); err != nil {
+				return err
+			}
+
+			fmt.Printf("Regenerated token and queued resubscribe for partition %d\n", *partition)
+			return nil
+		}
+
+		partitionCount, err := queueAdmin.PartitionCount(ctx, queueConfig.Topics[0])
+		if err != nil {
+			return fmt.Errorf("failed to discover partition count: %w", err)
+		}
+
+		var errs []error
+		for current := 0; current < partitionCount; current++ {
+			if err := conversationService.RegenerateToken(ctx, current); err != nil {
+				errs = append(errs, fmt.Errorf("partition %d: %w", current, err))
+				continue
+			}
+
+			fmt.Printf("Regenerated token and queued resubscribe for partition %d\n", current)
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+func listMappings(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("mappings list", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	return invoke(func(adminService service.AdminService) error {
+		mappings, err := adminService.FindMappings(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(mappings)
+	})
+}
+
+func inspectMapping(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("mappings inspect", flag.ExitOnError)
+	partition := flags.Int("partition", -1, "partition to inspect")
+	reveal := flags.Bool("reveal", false, "print the token unmasked")
+	_ = flags.Parse(args)
+
+	if *partition < 0 {
+		return errors.New("-partition is required")
+	}
+
+	return invoke(func(adminService service.AdminService, conversationMappingRepository repository.ConversationMappingRepository) error {
+		mappings, err := adminService.FindMappings(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mapping := range mappings {
+			if mapping.Partition != *partition {
+				continue
+			}
+
+			if *reveal {
+				conversationMapping, err := conversationMappingRepository.FindOneByPartition(ctx, *partition)
+				if err != nil {
+					return err
+				}
+				mapping.Token = conversationMapping.Token
+			}
+
+			return printJSON(mapping)
+		}
+
+		return fmt.Errorf("%w: %d", service.ErrPartitionNotFound, *partition)
+	})
+}
+
+func produce(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("produce", flag.ExitOnError)
+	file := flags.String("file", "", "JSON file holding a CreateConversationRequest")
+	conversationId := flags.String("conversation-id", "", "conversation id, generated when omitted")
+	_ = flags.Parse(args)
+
+	return invoke(func(salesforceConfig configs.SalesforceConfig, conversationService service.ConversationService) error {
+		req := request.CreateConversationRequest{
+			ConversationId:  fmt.Sprintf("ctl-%d", time.Now().UnixNano()),
+			EsDeveloperName: salesforceConfig.EsDeveloperName,
+			Language:        "en_US",
+			RoutingAttributes: request.CreateConversationRoutingAttributes{
+				CaseId:        "ctl-case",
+				AccountId:     "ctl-account",
+				CustomerName:  "Test Customer",
+				CustomerPhone: "+10000000000",
+				CustomerEmail: "test@example.com",
+				Origin:        "ctl",
+				SourceType:    "ctl",
+			},
+		}
+
+		if *file != "" {
+			data, err := os.ReadFile(*file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", *file, err)
+			}
+
+			if err := json.Unmarshal(data, &req); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", *file, err)
+			}
+		}
+
+		if *conversationId != "" {
+			req.ConversationId = *conversationId
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+
+		requestId, err := conversationService.CreateConversationProducer(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Produced create conversation %s with request id %s\n", req.ConversationId, requestId)
+
+		return nil
+	})
+}
+
+func tailSSE(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("sse tail", flag.ExitOnError)
+	partition := flags.Int("partition", -1, "partition whose stream to tail")
+	_ = flags.Parse(args)
+
+	if *partition < 0 {
+		return errors.New("-partition is required")
+	}
+
+	return invoke(func(tokenCache service.TokenCache, salesforceOutbound outbound.SalesforceOutbound) error {
+		token, err := tokenCache.Get(ctx, *partition)
+		if err != nil {
+			return err
+		}
+
+		onConnect := func() {
+			fmt.Printf("%s connected\n", time.Now().Format(time.RFC3339))
+		}
+
+		onEvent := func(eventType string, data []byte) {
+			fmt.Printf("%s %s %s\n", time.Now().Format(time.RFC3339), eventType, data)
+		}
+
+		if err := salesforceOutbound.Subscribe(ctx, token, onConnect, onEvent); err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func inspectDLQ(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("dlq inspect", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "messages to read per partition")
+	_ = flags.Parse(args)
+
+	return invoke(func(queueConfig configs.QueueConfig, queueAdmin library.QueueAdmin) error {
+		messages, err := readDLQ(ctx, queueConfig, queueAdmin, *limit)
+		if err != nil {
+			return err
+		}
+
+		results := make([]dlqMessage, 0, len(messages))
+		for _, message := range messages {
+			results = append(results, newDLQMessage(message))
+		}
+
+		return printJSON(results)
+	})
+}
+
+func replayDLQ(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("dlq replay", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "messages to read per partition")
+	partition := flags.Int("partition", -1, "replay only the DLQ message on this partition")
+	offset := flags.Int64("offset", -1, "replay only the DLQ message at this offset")
+	all := flags.Bool("all", false, "replay every DLQ message that was not replayed yet")
+	_ = flags.Parse(args)
+
+	if (*partition < 0) != (*offset < 0) {
+		return errors.New("-partition and -offset must be used together")
+	}
+
+	if *all == (*partition >= 0) {
+		return errors.New("either -partition and -offset, or -all is required")
+	}
+
+	return invoke(func(queueConfig configs.QueueConfig, queueAdmin library.QueueAdmin, queueProducer library.QueueProducer) error {
+		messages, err := readDLQ(ctx, queueConfig, queueAdmin, *limit)
+		if err != nil {
+			return err
+		}
+
+		group := queueConfig.GroupName + "-dlq-replay"
+		committed, err := queueAdmin.CommittedOffsets(ctx, group, queueConfig.DlqTopic)
+		if err != nil {
+			return fmt.Errorf("failed to read replayed offsets: %w", err)
+		}
+
+		sort.Slice(messages, func(i, j int) bool {
+			if messages[i].Partition != messages[j].Partition {
+				return messages[i].Partition < messages[j].Partition
+			}
+			return messages[i].Offset < messages[j].Offset
+		})
+
+		replayed := 0
+		for _, message := range messages {
+			if !*all && (message.Partition != int32(*partition) || message.Offset != *offset) {
+				continue
+			}
+
+			next, tracked := committed[message.Partition]
+			if tracked && message.Offset < next {
+				if !*all {
+					return fmt.Errorf("DLQ message %d/%d was already replayed", message.Partition, message.Offset)
+				}
+				continue
+			}
+
+			replay := &library.Message{
+				Topic: library.GetHeader(message, library.HeaderDlqOriginalTopic),
+				Key:   message.Key,
+				Value: message.Value,
+			}
+			if replay.Topic == "" {
+				replay.Topic = queueConfig.Topics[0]
+			}
+
+			for _, header := range message.Headers {
+				if !strings.HasPrefix(header.Key, "dlq-") && header.Key != library.HeaderReplyTopic && header.Key != library.HeaderCorrelationId {
+					replay.Headers = append(replay.Headers, header)
+				}
+			}
+
+			if _, _, err := queueProducer.Produce(ctx, replay); err != nil {
+				return fmt.Errorf("failed to replay %d/%d: %w", message.Partition, message.Offset, err)
+			}
+
+			fmt.Printf("Replayed %d/%d to %s\n", message.Partition, message.Offset, replay.Topic)
+			replayed++
+
+			if !*all && (!tracked || message.Offset != next) {
+				fmt.Printf("Replay of %d/%d not recorded, earlier DLQ messages on partition %d are still pending\n", message.Partition, message.Offset, message.Partition)
+				continue
+			}
+
+			committed[message.Partition] = message.Offset + 1
+			if err := queueAdmin.CommitOffsets(ctx, group, queueConfig.DlqTopic, map[int32]int64{message.Partition: message.Offset + 1}); err != nil {
+				return fmt.Errorf("failed to record replay of %d/%d: %w", message.Partition, message.Offset, err)
+			}
+		}
+
+		if replayed == 0 {
+			if *all {
+				fmt.Println("No DLQ messages left to replay")
+				return nil
+			}
+			return errors.New("no DLQ messages matched")
+		}
+
+		return nil
+	})
+}
+
+func resetOffsets(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("offsets reset", flag.ExitOnError)
+	timestamp := flags.String("timestamp", "", "RFC3339 timestamp to rewind or advance to")
+	group := flags.String("group", "", "consumer group, QUEUE_GROUP_NAME when omitted")
+	topic := flags.String("topic", "", "topic, the first of QUEUE_TOPICS when omitted")
+	_ = flags.Parse(args)
+
+	at, err := time.Parse(time.RFC3339, *timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid -timestamp: %w", err)
+	}
+
+	return invoke(func(queueConfig configs.QueueConfig, queueAdmin library.QueueAdmin) error {
+		if *group == "" {
+			*group = queueConfig.GroupName
+		}
+
+		if *topic == "" {
+			*topic = queueConfig.Topics[0]
+		}
+
+		if err := queueAdmin.ResetOffsets(ctx, *group, *topic, at); err != nil {
+			return err
+		}
+
+		fmt.Printf("Reset %s on %s to %s\n", *group, *topic, at.Format(time.RFC3339))
+
+		return nil
+	})
+}
+
+func migrate(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	return invokeMigrationService(invoke, func(migrationService service.MigrationService) error {
+		applied, err := migrationService.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(applied)
+	})
+}
+
+func migrationStatus(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("migrate status", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	return invokeMigrationService(invoke, func(migrationService service.MigrationService) error {
+		statuses, err := migrationService.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printJSON(statuses)
+	})
+}
+
+func invokeMigrationService(invoke invokeFunc, run func(migrationService service.MigrationService) error) error {
+	return invoke(func(storageConfig configs.StorageConfig) error {
+		if storageConfig.Backend != configs.StorageBackendMongo {
+			return fmt.Errorf("migrations require the %q storage backend, got %q", configs.StorageBackendMongo, storageConfig.Backend)
+		}
+
+		return invoke(run)
+	})
+}
+
+func createApiKey(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	name := flags.String("name", "", "name of the caller owning the key")
+	scopes := flags.String("scopes", model.ScopeConversationCreate, "comma separated scopes granted to the key")
+	_ = flags.Parse(args)
+
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+
+	return invoke(func(apiKeyRepository repository.ApiKeyRepository) error {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		key := base64.RawURLEncoding.EncodeToString(secret)
+
+		if _, err := apiKeyRepository.Upsert(ctx, model.ApiKey{
+			Name:      *name,
+			KeyHash:   middleware.HashApiKey(key),
+			Scopes:    strings.Split(*scopes, ","),
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		fmt.Println(key)
+
+		return nil
+	})
+}
+
+func reEncryptMappings(ctx context.Context, invoke invokeFunc, args []string) error {
+	flags := flag.NewFlagSet("mappings reencrypt", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	return invoke(func(conversationMappingRepository repository.ConversationMappingRepository) error {
+		count, err := conversationMappingRepository.ReEncrypt(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Re-encrypted %d conversation mappings\n", count)
+
+		return nil
+	})
+}
+
+func readDLQ(ctx context.Context, queueConfig configs.QueueConfig, queueAdmin library.QueueAdmin, limit int) ([]*library.Message, error) {
+	if queueConfig.DlqTopic == "" {
+		return nil, errors.New("QUEUE_DLQ_TOPIC is not configured")
+	}
+
+	return queueAdmin.ReadMessages(ctx, queueConfig.DlqTopic, limit)
+}
+
+func newDLQMessage(message *library.Message) dlqMessage {
+	result := dlqMessage{
+		Topic:     message.Topic,
+		Partition: message.Partition,
+		Offset:    message.Offset,
+		Timestamp: message.Timestamp,
+		Key:       string(message.Key),
+		Value:     string(message.Value),
+		Headers:   map[string]string{},
+	}
+
+	for _, header := range message.Headers {
+		result.Headers[header.Key] = string(header.Value)
+	}
+
+	return result
+}
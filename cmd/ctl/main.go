@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"salesforce-sse-worker/internal/di"
+	"syscall"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, invoke invokeFunc, args []string) error
+}
+
+type invokeFunc func(function interface{}) error
+
+var commands = map[string]command{
+	"token generate":     {usage: "[-partition N]", run: generateToken},
+	"mappings list":      {usage: "", run: listMappings},
+	"mappings inspect":   {usage: "-partition N [-reveal]", run: inspectMapping},
+	"mappings reencrypt": {usage: "", run: reEncryptMappings},
+	"apikey create":      {usage: "-name NAME [-scopes a,b]", run: createApiKey},
+	"produce":            {usage: "[-file request.json] [-conversation-id ID]", run: produce},
+	"sse tail":           {usage: "-partition N", run: tailSSE},
+	"dlq inspect":        {usage: "[-limit N]", run: inspectDLQ},
+	"dlq replay":         {usage: "[-limit N] (-partition N -offset N | -all)", run: replayDLQ},
+	"offsets reset":      {usage: "-timestamp RFC3339 [-group NAME] [-topic NAME]", run: resetOffsets},
+	"migrate":            {usage: "", run: migrate},
+	"migrate status":     {usage: "", run: migrationStatus},
+}
+
+func main() {
+	name, args, ok := lookupCommand(os.Args[1:])
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+
+	container, err := di.Provides()
+	if err != nil {
+		fail(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	invoke := func(function interface{}) error {
+		return container.Invoke(function)
+	}
+
+	if err := commands[name].run(ctx, invoke, args); err != nil {
+		fail(err)
+	}
+}
+
+func lookupCommand(args []string) (string, []string, bool) {
+	if len(args) >= 2 {
+		if _, ok := commands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], args[2:], true
+		}
+	}
+
+	if len(args) >= 1 {
+		if _, ok := commands[args[0]]; ok {
+			return args[0], args[1:], true
+		}
+	}
+
+	return "", nil, false
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: ctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	for _, name := range []string{"token generate", "mappings list", "mappings inspect", "mappings reencrypt", "apikey create", "produce", "sse tail", "dlq inspect", "dlq replay", "offsets reset", "migrate", "migrate status"} {
+		fmt.Fprintf(os.Stderr, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/dig"
+	"log/slog"
+	"os"
+	"os/signal"
+	"salesforce-sse-worker/internal/di"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/service"
+	"syscall"
+)
+
+type registerFunc func(container *dig.Container) error
+
+type runParams struct {
+	dig.In
+
+	Lifecycle        library.Lifecycle
+	MigrationService service.MigrationService `optional:"true"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	role := os.Args[1]
+
+	var registers []registerFunc
+	switch role {
+	case di.RoleServer:
+		registers = []registerFunc{registerServer}
+	case di.RoleWorker:
+		registers = []registerFunc{registerWorker}
+	case di.RoleAll:
+		registers = []registerFunc{registerServer, registerWorker}
+	default:
+		usage()
+	}
+
+	container, err := di.ProvidesRole(role)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	for _, register := range registers {
+		if err := register(container); err != nil {
+			panic(err.Error())
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := container.Invoke(func(params runParams) error {
+		return run(ctx, params.Lifecycle)
+	}); err != nil {
+		slog.Error("Error running "+role, slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, lifecycle library.Lifecycle) error {
+	if err := lifecycle.Start(ctx); err != nil {
+		return err
+	}
+
+	waitErr := lifecycle.Wait(ctx)
+
+	return errors.Join(waitErr, lifecycle.Stop(context.Background()))
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <%s|%s|%s>\n", os.Args[0], di.RoleServer, di.RoleWorker, di.RoleAll)
+	os.Exit(2)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/dig"
+	"log/slog"
+	"net"
+	"net/http"
+	"salesforce-sse-worker/internal/handler"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/middleware"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/service"
+)
+
+func registerServer(container *dig.Container) error {
+	return container.Invoke(func(lifecycle library.Lifecycle, messageHandler handler.ConversationHandler, webhookHandler handler.WebhookHandler, adminHandler handler.AdminHandler, authMiddleware middleware.AuthMiddleware, rateLimitMiddleware middleware.RateLimitMiddleware, backpressureMiddleware middleware.BackpressureMiddleware, outboxService service.OutboxService) {
+		e := echo.New()
+		e.HideBanner = true
+
+		e.POST("/conversation/token", messageHandler.GenerateToken, authMiddleware.Require(model.ScopeAdmin), rateLimitMiddleware.Limit())
+		e.POST("/conversation/create", messageHandler.CreateConversation, authMiddleware.Require(model.ScopeConversationCreate), rateLimitMiddleware.Limit(), backpressureMiddleware.Guard())
+		e.POST("/conversation/:conversationId/message", messageHandler.SendMessage, authMiddleware.Require(model.ScopeConversationWrite), rateLimitMiddleware.Limit(), backpressureMiddleware.Guard())
+		e.POST("/conversation/:conversationId/close", messageHandler.CloseConversation, authMiddleware.Require(model.ScopeConversationWrite), rateLimitMiddleware.Limit(), backpressureMiddleware.Guard())
+		e.POST("/conversation/:conversationId/typing", messageHandler.SendTyping, authMiddleware.Require(model.ScopeConversationWrite), rateLimitMiddleware.Limit(), backpressureMiddleware.Guard())
+		e.POST("/conversation/:conversationId/attachment", messageHandler.SendAttachment, authMiddleware.Require(model.ScopeConversationWrite), rateLimitMiddleware.Limit(), backpressureMiddleware.Guard())
+		e.GET("/webhook/deliveries", webhookHandler.FindDeliveries, authMiddleware.Require(model.ScopeAdmin))
+		e.GET("/webhook/deliveries/:id", webhookHandler.FindDelivery, authMiddleware.Require(model.ScopeAdmin))
+		e.POST("/webhook/deliveries/:id/redeliver", webhookHandler.Redeliver, authMiddleware.Require(model.ScopeAdmin))
+		e.GET("/admin/mappings", adminHandler.FindMappings, authMiddleware.Require(model.ScopeAdmin))
+		e.DELETE("/admin/mappings/orphaned", adminHandler.DeleteOrphaned, authMiddleware.Require(model.ScopeAdmin))
+		e.POST("/admin/mappings/:partition/token", adminHandler.RegenerateToken, authMiddleware.Require(model.ScopeAdmin))
+		e.POST("/admin/mappings/:partition/resubscribe", adminHandler.Resubscribe, authMiddleware.Require(model.ScopeAdmin))
+
+		lifecycle.AppendBackground("outbox relay", library.LifecycleOrderService, func(ctx context.Context) error {
+			outboxService.Relay(ctx)
+			return nil
+		})
+		serve(lifecycle, "server http", e, ":8888")
+	})
+}
+
+func serve(lifecycle library.Lifecycle, name string, e *echo.Echo, addr string) {
+	lifecycle.Append(library.LifecycleHook{
+		Name:  name,
+		Order: library.LifecycleOrderHttp,
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			e.Listener = listener
+
+			go func() {
+				if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					slog.Error("HTTP server stopped", slog.String("component", name), slog.Any("error", err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return e.Shutdown(ctx)
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/dig"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/handler"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/middleware"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/service"
+)
+
+func registerWorker(container *dig.Container) error {
+	return container.Invoke(func(lifecycle library.Lifecycle, workerConfig configs.WorkerConfig, authConfig configs.AuthConfig, queueConsumer library.QueueConsumer, conversationService service.ConversationService, tokenCache service.TokenCache, workerHandler handler.WorkerHandler, authMiddleware middleware.AuthMiddleware) error {
+		if workerConfig.HttpEnabled && !authConfig.Enabled {
+			return errors.New("WORKER_HTTP_ENABLED requires AUTH_ENABLED")
+		}
+
+		lifecycle.AppendBackground("partition watcher", library.LifecycleOrderService, func(ctx context.Context) error {
+			conversationService.WatchPartitions(ctx, func() bool {
+				return ownsPartition(queueConsumer.Status(), 0)
+			})
+			return nil
+		})
+		lifecycle.AppendBackground("token cache watcher", library.LifecycleOrderService, func(ctx context.Context) error {
+			tokenCache.Watch(ctx)
+			return nil
+		})
+
+		if workerConfig.HttpEnabled {
+			e := echo.New()
+			e.HideBanner = true
+
+			e.GET("/worker/status", workerHandler.Status, authMiddleware.Require(model.ScopeAdmin))
+			e.POST("/worker/partitions/:partition/pause", workerHandler.Pause, authMiddleware.Require(model.ScopeAdmin))
+			e.POST("/worker/partitions/:partition/resume", workerHandler.Resume, authMiddleware.Require(model.ScopeAdmin))
+
+			serve(lifecycle, "worker http", e, workerConfig.HttpAddr)
+		}
+
+		return nil
+	})
+}
+
+func ownsPartition(status library.ConsumerStatus, partition int32) bool {
+	for _, partitionStatus := range status.Partitions {
+		if partitionStatus.Partition == partition {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,21 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+type AuthConfig struct {
+	Enabled      bool   `envconfig:"ENABLED" default:"true"`
+	ApiKeyHeader string `envconfig:"API_KEY_HEADER" default:"X-Api-Key"`
+	JwksFile     string `envconfig:"JWKS_FILE"`
+	Issuer       string `envconfig:"ISSUER"`
+	Audience     string `envconfig:"AUDIENCE"`
+	ScopeClaim   string `envconfig:"SCOPE_CLAIM" default:"scope"`
+}
+
+func NewAuthConfig(e EnvFileRead) (AuthConfig, error) {
+	var cfg AuthConfig
+	if err := envconfig.Process("AUTH", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
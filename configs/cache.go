@@ -0,0 +1,16 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+type CacheConfig struct {
+	TokenChangeStream bool `envconfig:"TOKEN_CHANGE_STREAM"`
+}
+
+func NewCacheConfig(e EnvFileRead) (CacheConfig, error) {
+	var cfg CacheConfig
+	if err := envconfig.Process("CACHE", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,71 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+	"os"
+)
+
+type EncryptionConfig struct {
+	KeyFile     string            `envconfig:"KEY_FILE"`
+	Keys        map[string]string `envconfig:"KEYS"`
+	ActiveKeyId string            `envconfig:"ACTIVE_KEY_ID"`
+}
+
+type PayloadEncryptionConfig struct {
+	Enabled bool `envconfig:"ENABLED"`
+	EncryptionConfig
+}
+
+type encryptionKeyFile struct {
+	ActiveKeyId string            `json:"activeKeyId"`
+	Keys        map[string]string `json:"keys"`
+}
+
+func NewEncryptionConfig(e EnvFileRead) (EncryptionConfig, error) {
+	var cfg EncryptionConfig
+	if err := envconfig.Process("ENCRYPTION", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, loadEncryptionKeyFile(&cfg)
+}
+
+func NewPayloadEncryptionConfig(e EnvFileRead) (PayloadEncryptionConfig, error) {
+	var cfg PayloadEncryptionConfig
+	if err := envconfig.Process("PAYLOAD_ENCRYPTION", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, loadEncryptionKeyFile(&cfg.EncryptionConfig)
+}
+
+func loadEncryptionKeyFile(cfg *EncryptionConfig) error {
+	if cfg.KeyFile == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read encryption key file: %w", err)
+	}
+
+	var keyFile encryptionKeyFile
+	if err := json.Unmarshal(content, &keyFile); err != nil {
+		return fmt.Errorf("failed to decode encryption key file: %w", err)
+	}
+
+	if cfg.Keys == nil {
+		cfg.Keys = map[string]string{}
+	}
+	for keyId, key := range keyFile.Keys {
+		cfg.Keys[keyId] = key
+	}
+
+	if cfg.ActiveKeyId == "" {
+		cfg.ActiveKeyId = keyFile.ActiveKeyId
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package configs
+
+import (
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+)
+
+type RateLimitConfig struct {
+	Enabled   bool    `envconfig:"ENABLED"`
+	Rate      float64 `envconfig:"RATE" default:"10"`
+	Burst     int     `envconfig:"BURST" default:"20"`
+	ExpiresIn int     `envconfig:"EXPIRES_IN" default:"180000"`
+}
+
+type BackpressureConfig struct {
+	Enabled         bool  `envconfig:"ENABLED"`
+	LagThreshold    int64 `envconfig:"LAG_THRESHOLD" default:"10000"`
+	RetryAfter      int   `envconfig:"RETRY_AFTER" default:"30"`
+	RefreshInterval int   `envconfig:"REFRESH_INTERVAL" default:"5000"`
+	MaxLagAge       int   `envconfig:"MAX_LAG_AGE" default:"30000"`
+}
+
+func NewRateLimitConfig(e EnvFileRead) (RateLimitConfig, error) {
+	var cfg RateLimitConfig
+	if err := envconfig.Process("RATE_LIMIT", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func NewBackpressureConfig(e EnvFileRead) (BackpressureConfig, error) {
+	var cfg BackpressureConfig
+	if err := envconfig.Process("BACKPRESSURE", &cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Enabled && cfg.RefreshInterval <= 0 {
+		return cfg, fmt.Errorf("BACKPRESSURE_REFRESH_INTERVAL must be positive, got %d", cfg.RefreshInterval)
+	}
+	if cfg.Enabled && cfg.MaxLagAge <= 0 {
+		return cfg, fmt.Errorf("BACKPRESSURE_MAX_LAG_AGE must be positive, got %d", cfg.MaxLagAge)
+	}
+
+	return cfg, nil
+}
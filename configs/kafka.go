@@ -1,15 +1,40 @@
 package configs
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"github.com/IBM/sarama"
 	"github.com/kelseyhightower/envconfig"
+	"os"
 )
 
+type saramaPartitioner struct {
+	hash sarama.Partitioner
+}
+
 type KafkaConfig struct {
-	Brokers        []string `envconfig:"BROKERS"`
-	Topics         []string `envconfig:"TOPICS"`
-	GroupName      string   `envconfig:"GROUP_NAME"`
-	PartitionCount int      `envconfig:"PARTITION_COUNT"`
+	Brokers                []string `envconfig:"BROKERS"`
+	ClientId               string   `envconfig:"CLIENT_ID" default:"salesforce-sse-worker"`
+	Version                string   `envconfig:"VERSION"`
+	TLSEnabled             bool     `envconfig:"TLS_ENABLED"`
+	TLSCaFile              string   `envconfig:"TLS_CA_FILE"`
+	TLSCertFile            string   `envconfig:"TLS_CERT_FILE"`
+	TLSKeyFile             string   `envconfig:"TLS_KEY_FILE"`
+	TLSSkipVerify          bool     `envconfig:"TLS_SKIP_VERIFY"`
+	SASLMechanism          string   `envconfig:"SASL_MECHANISM"`
+	SASLUsername           string   `envconfig:"SASL_USERNAME"`
+	SASLPassword           string   `envconfig:"SASL_PASSWORD"`
+	ProducerAcks           string   `envconfig:"PRODUCER_ACKS" default:"all"`
+	ProducerCompression    string   `envconfig:"PRODUCER_COMPRESSION" default:"none"`
+	ProducerIdempotent     bool     `envconfig:"PRODUCER_IDEMPOTENT"`
+	ProducerMode           string   `envconfig:"PRODUCER_MODE" default:"sync"`
+	ProducerFlushMessages  int      `envconfig:"PRODUCER_FLUSH_MESSAGES"`
+	ProducerFlushBytes     int      `envconfig:"PRODUCER_FLUSH_BYTES"`
+	ProducerFlushFrequency int      `envconfig:"PRODUCER_FLUSH_FREQUENCY"`
+	RebalanceStrategy      string   `envconfig:"REBALANCE_STRATEGY" default:"roundrobin"`
+	GroupInstanceId        string   `envconfig:"GROUP_INSTANCE_ID"`
+	SessionTimeout         int      `envconfig:"SESSION_TIMEOUT" default:"10000"`
 }
 
 func NewKafkaConfig(e EnvFileRead) (KafkaConfig, error) {
@@ -21,12 +46,182 @@ func NewKafkaConfig(e EnvFileRead) (KafkaConfig, error) {
 	return cfg, nil
 }
 
-func NewSaramaConfig(e EnvFileRead) *sarama.Config {
+func NewSaramaConfig(cfg KafkaConfig) (*sarama.Config, error) {
 	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = cfg.ClientId
 	saramaConfig.Producer.Return.Successes = true
-	saramaConfig.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{
-		sarama.NewBalanceStrategyRoundRobin(),
+	saramaConfig.Producer.Partitioner = newSaramaPartitioner
+
+	if cfg.Version != "" {
+		version, err := sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Version = version
+	}
+
+	if err := setSaramaConsumerGroup(saramaConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := setSaramaTLS(saramaConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := setSaramaSASL(saramaConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	if err := setSaramaProducer(saramaConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	return saramaConfig, saramaConfig.Validate()
+}
+
+func setSaramaConsumerGroup(saramaConfig *sarama.Config, cfg KafkaConfig) error {
+	switch cfg.RebalanceStrategy {
+	case "", "roundrobin":
+		saramaConfig.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	case "range":
+		saramaConfig.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+	case "sticky":
+		saramaConfig.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	case "cooperative-sticky":
+		return fmt.Errorf("rebalance strategy %q is not supported by the Kafka client, use sticky with KAFKA_GROUP_INSTANCE_ID", cfg.RebalanceStrategy)
+	default:
+		return fmt.Errorf("unsupported rebalance strategy %q", cfg.RebalanceStrategy)
 	}
 
-	return saramaConfig
+	if cfg.SessionTimeout > 0 {
+		saramaConfig.Consumer.Group.Session.Timeout = getDurationFromMilliseconds(cfg.SessionTimeout)
+		saramaConfig.Consumer.Group.Heartbeat.Interval = saramaConfig.Consumer.Group.Session.Timeout / 3
+	}
+
+	if cfg.GroupInstanceId != "" {
+		if !saramaConfig.Version.IsAtLeast(sarama.V2_3_0_0) {
+			saramaConfig.Version = sarama.V2_3_0_0
+		}
+		saramaConfig.Consumer.Group.InstanceId = cfg.GroupInstanceId
+	}
+
+	return nil
+}
+
+func setSaramaTLS(saramaConfig *sarama.Config, cfg KafkaConfig) error {
+	if !cfg.TLSEnabled {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSSkipVerify,
+	}
+
+	if cfg.TLSCaFile != "" {
+		ca, err := os.ReadFile(cfg.TLSCaFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Kafka CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in %s", cfg.TLSCaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load Kafka client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	saramaConfig.Net.TLS.Enable = true
+	saramaConfig.Net.TLS.Config = tlsConfig
+
+	return nil
+}
+
+func setSaramaSASL(saramaConfig *sarama.Config, cfg KafkaConfig) error {
+	if cfg.SASLMechanism == "" {
+		return nil
+	}
+
+	saramaConfig.Net.SASL.Enable = true
+	saramaConfig.Net.SASL.User = cfg.SASLUsername
+	saramaConfig.Net.SASL.Password = cfg.SASLPassword
+
+	switch cfg.SASLMechanism {
+	case sarama.SASLTypePlaintext:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case sarama.SASLTypeSCRAMSHA256:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scramSHA256}
+		}
+	case sarama.SASLTypeSCRAMSHA512:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scramSHA512}
+		}
+	default:
+		return fmt.Errorf("unsupported SASL mechanism %q", cfg.SASLMechanism)
+	}
+
+	return nil
+}
+
+func setSaramaProducer(saramaConfig *sarama.Config, cfg KafkaConfig) error {
+	switch cfg.ProducerAcks {
+	case "", "all":
+		saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	case "local":
+		saramaConfig.Producer.RequiredAcks = sarama.WaitForLocal
+	case "none":
+		saramaConfig.Producer.RequiredAcks = sarama.NoResponse
+	default:
+		return fmt.Errorf("unsupported producer acks %q", cfg.ProducerAcks)
+	}
+
+	if err := saramaConfig.Producer.Compression.UnmarshalText([]byte(cfg.ProducerCompression)); err != nil {
+		return fmt.Errorf("unsupported producer compression %q: %w", cfg.ProducerCompression, err)
+	}
+
+	saramaConfig.Producer.Flush.Messages = cfg.ProducerFlushMessages
+	saramaConfig.Producer.Flush.Bytes = cfg.ProducerFlushBytes
+	saramaConfig.Producer.Flush.Frequency = getDurationFromMilliseconds(cfg.ProducerFlushFrequency)
+
+	if cfg.ProducerIdempotent {
+		if !saramaConfig.Version.IsAtLeast(sarama.V0_11_0_0) {
+			saramaConfig.Version = sarama.V0_11_0_0
+		}
+		saramaConfig.Producer.Idempotent = true
+		saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+		saramaConfig.Net.MaxOpenRequests = 1
+	}
+
+	return nil
+}
+
+func newSaramaPartitioner(topic string) sarama.Partitioner {
+	return &saramaPartitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+func (p *saramaPartitioner) Partition(message *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Partition < 0 {
+		return p.hash.Partition(message, numPartitions)
+	}
+
+	if message.Partition >= numPartitions {
+		return -1, sarama.ErrInvalidPartition
+	}
+
+	return message.Partition, nil
+}
+
+func (p *saramaPartitioner) RequiresConsistency() bool {
+	return true
 }
@@ -0,0 +1,17 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+type LifecycleConfig struct {
+	StartTimeout int `envconfig:"START_TIMEOUT" default:"15000"`
+	StopTimeout  int `envconfig:"STOP_TIMEOUT" default:"15000"`
+}
+
+func NewLifecycleConfig(e EnvFileRead) (LifecycleConfig, error) {
+	var cfg LifecycleConfig
+	if err := envconfig.Process("LIFECYCLE", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
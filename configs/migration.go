@@ -0,0 +1,16 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+type MigrationConfig struct {
+	OnStartup bool `envconfig:"ON_STARTUP" default:"true"`
+}
+
+func NewMigrationConfig(e EnvFileRead) (MigrationConfig, error) {
+	var cfg MigrationConfig
+	if err := envconfig.Process("MIGRATION", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,32 @@
+package configs
+
+import (
+	"errors"
+	"github.com/kelseyhightower/envconfig"
+)
+
+type OutboxConfig struct {
+	Enabled       bool `envconfig:"ENABLED"`
+	PollInterval  int  `envconfig:"POLL_INTERVAL" default:"1000"`
+	BatchSize     int  `envconfig:"BATCH_SIZE" default:"100"`
+	LeaseTimeout  int  `envconfig:"LEASE_TIMEOUT" default:"15000"`
+	SentRetention int  `envconfig:"SENT_RETENTION" default:"86400000"`
+	PurgeInterval int  `envconfig:"PURGE_INTERVAL" default:"3600000"`
+}
+
+func NewOutboxConfig(e EnvFileRead) (OutboxConfig, error) {
+	var cfg OutboxConfig
+	if err := envconfig.Process("OUTBOX", &cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Enabled && (cfg.PollInterval <= 0 || cfg.BatchSize <= 0 || cfg.LeaseTimeout <= 0 || cfg.PurgeInterval <= 0) {
+		return cfg, errors.New("OUTBOX_POLL_INTERVAL, OUTBOX_BATCH_SIZE, OUTBOX_LEASE_TIMEOUT and OUTBOX_PURGE_INTERVAL must be positive")
+	}
+
+	if cfg.Enabled && cfg.PollInterval >= cfg.LeaseTimeout {
+		return cfg, errors.New("OUTBOX_POLL_INTERVAL must be shorter than OUTBOX_LEASE_TIMEOUT")
+	}
+
+	return cfg, nil
+}
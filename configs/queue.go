@@ -0,0 +1,62 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kelseyhightower/envconfig"
+)
+
+const (
+	QueueBackendKafka  = "kafka"
+	QueueBackendMemory = "memory"
+	QueueBackendRedis  = "redis"
+)
+
+type (
+	QueueConfig struct {
+		Backend                  string   `envconfig:"BACKEND" default:"kafka"`
+		Topics                   []string `envconfig:"TOPICS"`
+		GroupName                string   `envconfig:"GROUP_NAME"`
+		PartitionRefreshInterval int      `envconfig:"PARTITION_REFRESH_INTERVAL" default:"60000"`
+		ReplyTopic               string   `envconfig:"REPLY_TOPIC"`
+		ReplyMaxWait             int      `envconfig:"REPLY_MAX_WAIT" default:"30000"`
+		ResultTopic              string   `envconfig:"RESULT_TOPIC"`
+		DlqTopic                 string   `envconfig:"DLQ_TOPIC"`
+		MemoryPartitions         int      `envconfig:"MEMORY_PARTITIONS" default:"8"`
+	}
+
+	legacyQueueConfig struct {
+		Topics    []string `envconfig:"TOPICS"`
+		GroupName string   `envconfig:"GROUP_NAME"`
+	}
+)
+
+func NewQueueConfig(e EnvFileRead) (QueueConfig, error) {
+	var cfg QueueConfig
+	if err := envconfig.Process("QUEUE", &cfg); err != nil {
+		return cfg, err
+	}
+
+	var legacy legacyQueueConfig
+	if err := envconfig.Process("KAFKA", &legacy); err != nil {
+		return cfg, err
+	}
+
+	if len(cfg.Topics) == 0 {
+		cfg.Topics = legacy.Topics
+	}
+
+	if cfg.GroupName == "" {
+		cfg.GroupName = legacy.GroupName
+	}
+
+	if len(cfg.Topics) == 0 {
+		return cfg, errors.New("QUEUE_TOPICS is required")
+	}
+
+	if cfg.PartitionRefreshInterval <= 0 {
+		return cfg, fmt.Errorf("QUEUE_PARTITION_REFRESH_INTERVAL must be positive, got %d", cfg.PartitionRefreshInterval)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,37 @@
+package configs
+
+import (
+	"github.com/kelseyhightower/envconfig"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisConfig struct {
+	Addr          string `envconfig:"ADDR" default:"localhost:6379"`
+	Username      string `envconfig:"USERNAME"`
+	Password      string `envconfig:"PASSWORD"`
+	DB            int    `envconfig:"DB"`
+	Shards        int    `envconfig:"SHARDS" default:"8"`
+	StreamMaxLen  int    `envconfig:"STREAM_MAX_LEN"`
+	BlockTimeout  int    `envconfig:"BLOCK_TIMEOUT" default:"5000"`
+	ClaimMinIdle  int    `envconfig:"CLAIM_MIN_IDLE" default:"30000"`
+	LeaseTimeout  int    `envconfig:"LEASE_TIMEOUT" default:"15000"`
+	ReadBatchSize int    `envconfig:"READ_BATCH_SIZE" default:"10"`
+}
+
+func NewRedisConfig(e EnvFileRead) (RedisConfig, error) {
+	var cfg RedisConfig
+	if err := envconfig.Process("REDIS", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+func NewRedisClientConfig(cfg RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+}
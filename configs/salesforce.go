@@ -3,8 +3,13 @@ package configs
 import "github.com/kelseyhightower/envconfig"
 
 type SalesforceConfig struct {
-	Host  string `envconfig:"HOST"`
-	OrgId string `envconfig:"ORG_ID"`
+	Host                string `envconfig:"HOST"`
+	OrgId               string `envconfig:"ORG_ID"`
+	EsDeveloperName     string `envconfig:"ES_DEVELOPER_NAME"`
+	CapabilitiesVersion string `envconfig:"CAPABILITIES_VERSION"`
+	Platform            string `envconfig:"PLATFORM"`
+	AppName             string `envconfig:"APP_NAME"`
+	ClientVersion       string `envconfig:"CLIENT_VERSION"`
 }
 
 func NewSalesforceConfig(e EnvFileRead) (SalesforceConfig, error) {
@@ -0,0 +1,19 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+type SerializerConfig struct {
+	Format                 string `envconfig:"FORMAT" default:"json"`
+	SchemaRegistryUrl      string `envconfig:"SCHEMA_REGISTRY_URL"`
+	SchemaRegistryUsername string `envconfig:"SCHEMA_REGISTRY_USERNAME"`
+	SchemaRegistryPassword string `envconfig:"SCHEMA_REGISTRY_PASSWORD"`
+}
+
+func NewSerializerConfig(e EnvFileRead) (SerializerConfig, error) {
+	var cfg SerializerConfig
+	if err := envconfig.Process("SERIALIZER", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
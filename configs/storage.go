@@ -0,0 +1,24 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+const (
+	StorageBackendMongo  = "mongo"
+	StorageBackendSqlite = "sqlite"
+	StorageBackendMemory = "memory"
+)
+
+type StorageConfig struct {
+	Backend        string `envconfig:"BACKEND" default:"mongo"`
+	MappingBackend string `envconfig:"MAPPING_BACKEND" default:"mongo"`
+	SqlitePath     string `envconfig:"SQLITE_PATH" default:"salesforce-sse-worker.db"`
+}
+
+func NewStorageConfig(e EnvFileRead) (StorageConfig, error) {
+	var cfg StorageConfig
+	if err := envconfig.Process("STORAGE", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,38 @@
+package configs
+
+import (
+	"errors"
+	"github.com/kelseyhightower/envconfig"
+)
+
+type WebhookConfig struct {
+	Secret         string   `envconfig:"SECRET"`
+	AllowedHosts   []string `envconfig:"ALLOWED_HOSTS"`
+	MaxAttempts    int      `envconfig:"MAX_ATTEMPTS" default:"5"`
+	InitialBackoff int      `envconfig:"INITIAL_BACKOFF" default:"1000"`
+	MaxBackoff     int      `envconfig:"MAX_BACKOFF" default:"60000"`
+	Timeout        int      `envconfig:"TIMEOUT" default:"10000"`
+	RelayInterval  int      `envconfig:"RELAY_INTERVAL" default:"5000"`
+	RelayBatchSize int      `envconfig:"RELAY_BATCH_SIZE" default:"10"`
+}
+
+func NewWebhookConfig(e EnvFileRead) (WebhookConfig, error) {
+	var cfg WebhookConfig
+	if err := envconfig.Process("WEBHOOK", &cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Enabled() && cfg.Secret == "" {
+		return cfg, errors.New("WEBHOOK_SECRET is required when WEBHOOK_ALLOWED_HOSTS is set")
+	}
+
+	if cfg.Enabled() && (cfg.RelayInterval <= 0 || cfg.RelayBatchSize <= 0) {
+		return cfg, errors.New("WEBHOOK_RELAY_INTERVAL and WEBHOOK_RELAY_BATCH_SIZE must be positive")
+	}
+
+	return cfg, nil
+}
+
+func (c WebhookConfig) Enabled() bool {
+	return len(c.AllowedHosts) > 0
+}
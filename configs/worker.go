@@ -0,0 +1,19 @@
+package configs
+
+import "github.com/kelseyhightower/envconfig"
+
+type WorkerConfig struct {
+	HttpEnabled  bool   `envconfig:"HTTP_ENABLED" default:"false"`
+	HttpAddr     string `envconfig:"HTTP_ADDR" default:":8889"`
+	MaxAttempts  int    `envconfig:"MAX_ATTEMPTS" default:"1"`
+	RetryBackoff int    `envconfig:"RETRY_BACKOFF" default:"1000"`
+}
+
+func NewWorkerConfig(e EnvFileRead) (WorkerConfig, error) {
+	var cfg WorkerConfig
+	if err := envconfig.Process("WORKER", &cfg); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
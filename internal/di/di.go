@@ -2,10 +2,12 @@ package di
 
 import (
 	"errors"
+	"fmt"
 	"go.uber.org/dig"
 	"salesforce-sse-worker/configs"
 	"salesforce-sse-worker/internal/handler"
 	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/middleware"
 	"salesforce-sse-worker/internal/repository"
 	"salesforce-sse-worker/internal/service"
 	"salesforce-sse-worker/internal/service/outbound"
@@ -25,9 +27,34 @@ func (r *registry) GetError() error {
 	return errors.Join(r.errors...)
 }
 
+const (
+	RoleServer = "server"
+	RoleWorker = "worker"
+	RoleAll    = "all"
+)
+
 func Provides() (*dig.Container, error) {
+	return ProvidesRole(RoleAll)
+}
+
+func ProvidesRole(role string) (*dig.Container, error) {
 	r := registry{container: dig.New(), errors: []error{}}
 	provides(&r)
+	providesStorage(&r, role)
+	providesQueue(&r)
+
+	switch role {
+	case RoleServer:
+		providesServer(&r)
+	case RoleWorker:
+		providesWorker(&r)
+		r.provide(library.NewDisabledReplyListener)
+	case RoleAll:
+		providesServer(&r)
+		providesWorker(&r)
+	default:
+		return nil, fmt.Errorf("unsupported role %q", role)
+	}
 
 	if err := r.GetError(); err != nil {
 		return nil, err
@@ -39,22 +66,137 @@ func Provides() (*dig.Container, error) {
 func provides(r *registry) {
 	r.provide(configs.ReadEnvFile)
 	r.provide(configs.NewKafkaConfig)
-	r.provide(configs.NewSaramaConfig)
 	r.provide(configs.NewMongoConfig)
-	r.provide(configs.NewMongoClientConfig)
 	r.provide(configs.NewSalesforceConfig)
+	r.provide(configs.NewCacheConfig)
+	r.provide(configs.NewEncryptionConfig)
+	r.provide(configs.NewPayloadEncryptionConfig)
+	r.provide(configs.NewAuthConfig)
+	r.provide(configs.NewRateLimitConfig)
+	r.provide(configs.NewBackpressureConfig)
+	r.provide(configs.NewWebhookConfig)
+	r.provide(configs.NewSerializerConfig)
+	r.provide(configs.NewOutboxConfig)
+	r.provide(configs.NewQueueConfig)
+	r.provide(configs.NewRedisConfig)
+	r.provide(configs.NewWorkerConfig)
+	r.provide(configs.NewLifecycleConfig)
+	r.provide(configs.NewMigrationConfig)
+	r.provide(configs.NewStorageConfig)
 
+	r.provide(library.NewCipher)
+	r.provide(library.NewFieldCipher)
 	r.provide(library.NewHTTPClient)
-	r.provide(library.NewKafkaProducer)
-	r.provide(library.NewKafkaConsumer)
-	r.provide(library.NewMongoDatabase)
+	r.provide(library.NewJWTVerifier)
+	r.provide(library.NewLifecycle)
+	r.provide(library.NewQueueAdmin)
+	r.provide(library.NewQueueProducer)
+	r.provide(library.NewSchemaRegistry)
+	r.provide(library.NewSerializer)
 
-	r.provide(repository.NewConversationMappingRepository)
+	r.provide(repository.NewApiKeyRepository)
+	r.provide(repository.NewConversationCallbackRepository)
+	r.provide(repository.NewWebhookDeliveryRepository)
+	r.provide(repository.NewOutboxRepository)
+	r.provide(repository.NewSseStatusRepository)
 
-	r.provide(handler.NewKafkaHandler)
-	r.provide(handler.NewConversationHandler)
+	r.provide(middleware.NewAuthMiddleware)
 
 	r.provide(outbound.NewSalesforceOutbound)
 
+	r.provide(service.NewTokenCache)
+	r.provide(service.NewWebhookService)
+	r.provide(service.NewOutboxService)
 	r.provide(service.NewConversationService)
 }
+
+func providesStorage(r *registry, role string) {
+	err := r.container.Invoke(func(storageConfig configs.StorageConfig) error {
+		switch storageConfig.Backend {
+		case configs.StorageBackendMongo:
+			r.provide(configs.NewMongoClientConfig)
+			r.provide(library.NewMongoDatabase)
+			r.provide(repository.NewSchemaMigrationRepository)
+			r.provide(service.NewMigrationService)
+		case configs.StorageBackendMemory:
+			if role != RoleAll {
+				return fmt.Errorf("storage backend %q only supports the %q role", storageConfig.Backend, RoleAll)
+			}
+			if storageConfig.MappingBackend == configs.StorageBackendMongo {
+				return fmt.Errorf("mapping storage backend %q requires the %q storage backend", storageConfig.MappingBackend, configs.StorageBackendMongo)
+			}
+
+			r.provide(library.NewMemoryMongoDatabase)
+		default:
+			return fmt.Errorf("unsupported storage backend %q", storageConfig.Backend)
+		}
+
+		switch storageConfig.MappingBackend {
+		case configs.StorageBackendMongo:
+			r.provide(repository.NewMongoConversationMappingRepository)
+			return nil
+		case configs.StorageBackendSqlite, configs.StorageBackendMemory:
+			if role != RoleAll {
+				return fmt.Errorf("mapping storage backend %q only supports the %q role", storageConfig.MappingBackend, RoleAll)
+			}
+		default:
+			return fmt.Errorf("unsupported mapping storage backend %q", storageConfig.MappingBackend)
+		}
+
+		if storageConfig.MappingBackend == configs.StorageBackendSqlite {
+			r.provide(library.NewSqliteDatabase)
+			r.provide(repository.NewSqliteConversationMappingRepository)
+		} else {
+			r.provide(repository.NewMemoryConversationMappingRepository)
+		}
+
+		return nil
+	})
+	r.errors = append(r.errors, err)
+}
+
+func providesQueue(r *registry) {
+	err := r.container.Invoke(func(queueConfig configs.QueueConfig) error {
+		switch queueConfig.Backend {
+		case configs.QueueBackendKafka:
+			r.provide(configs.NewSaramaConfig)
+			r.provide(library.NewKafkaQueueBackend)
+		case configs.QueueBackendMemory:
+			r.provide(library.NewMemoryBroker)
+			r.provide(library.NewMemoryQueueBackend)
+		case configs.QueueBackendRedis:
+			r.provide(configs.NewRedisClientConfig)
+			r.provide(library.NewRedisQueueBackend)
+		default:
+			return fmt.Errorf("unsupported queue backend %q", queueConfig.Backend)
+		}
+
+		return nil
+	})
+	r.errors = append(r.errors, err)
+}
+
+func providesServer(r *registry) {
+	r.provide(library.NewQueueReplyListener)
+
+	r.provide(middleware.NewRateLimitMiddleware)
+	r.provide(middleware.NewBackpressureMiddleware)
+
+	r.provide(handler.NewConversationHandler)
+	r.provide(handler.NewWebhookHandler)
+	r.provide(handler.NewAdminHandler)
+
+	r.provide(service.NewAdminService)
+}
+
+func providesWorker(r *registry) {
+	r.provide(library.NewQueueConsumer)
+
+	r.provide(handler.NewCommandRegistry)
+	r.provide(handler.NewQueueHandler)
+	r.provide(handler.NewWorkerHandler)
+
+	r.provide(service.NewMessagingService)
+	r.provide(service.NewSubscriptionService)
+	r.provide(service.NewWorkerService)
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"salesforce-sse-worker/internal/service"
+	"strconv"
+)
+
+type AdminHandler interface {
+	FindMappings(e echo.Context) error
+	RegenerateToken(e echo.Context) error
+	DeleteOrphaned(e echo.Context) error
+	Resubscribe(e echo.Context) error
+}
+
+type AdminHandlerImpl struct {
+	adminService service.AdminService
+}
+
+func NewAdminHandler(adminService service.AdminService) AdminHandler {
+	return &AdminHandlerImpl{adminService: adminService}
+}
+
+func (a *AdminHandlerImpl) FindMappings(e echo.Context) error {
+	resp, err := a.adminService.FindMappings(e.Request().Context())
+	if err != nil {
+		return e.JSON(500, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(200, resp)
+}
+
+func (a *AdminHandlerImpl) RegenerateToken(e echo.Context) error {
+	partition, err := strconv.Atoi(e.Param("partition"))
+	if err != nil {
+		return e.JSON(400, map[string]string{"error": "Invalid partition"})
+	}
+
+	if err := a.adminService.RegenerateToken(e.Request().Context(), partition); err != nil {
+		return a.error(e, err)
+	}
+
+	return e.JSON(200, "Successfully regenerated token and queued resubscribe")
+}
+
+func (a *AdminHandlerImpl) DeleteOrphaned(e echo.Context) error {
+	resp, err := a.adminService.DeleteOrphaned(e.Request().Context())
+	if err != nil {
+		return e.JSON(500, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(200, resp)
+}
+
+func (a *AdminHandlerImpl) Resubscribe(e echo.Context) error {
+	partition, err := strconv.Atoi(e.Param("partition"))
+	if err != nil {
+		return e.JSON(400, map[string]string{"error": "Invalid partition"})
+	}
+
+	if err := a.adminService.Resubscribe(e.Request().Context(), partition); err != nil {
+		return a.error(e, err)
+	}
+
+	return e.JSON(200, "Resubscribe successfully queued")
+}
+
+func (a *AdminHandlerImpl) error(e echo.Context, err error) error {
+	if errors.Is(err, service.ErrPartitionNotFound) {
+		return e.JSON(404, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(500, map[string]string{"error": err.Error()})
+}
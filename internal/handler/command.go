@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/service"
+	"sync"
+)
+
+type (
+	CommandHandler func(ctx context.Context, envelope request.Envelope, message *library.Message) error
+
+	CommandRegistry interface {
+		Register(commandType string, handler CommandHandler)
+		Lookup(commandType string) (CommandHandler, bool)
+	}
+
+	CommandRegistryImpl struct {
+		mu       sync.RWMutex
+		handlers map[string]CommandHandler
+	}
+)
+
+func NewCommandRegistry() CommandRegistry {
+	return &CommandRegistryImpl{handlers: map[string]CommandHandler{}}
+}
+
+func (r *CommandRegistryImpl) Register(commandType string, handler CommandHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[commandType] = handler
+}
+
+func (r *CommandRegistryImpl) Lookup(commandType string) (CommandHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handler, ok := r.handlers[commandType]
+	return handler, ok
+}
+
+func (c *QueueHandlerImpl) registerCommands() {
+	c.commandRegistry.Register(request.CommandCreateConversation, c.createConversation)
+	c.commandRegistry.Register(request.CommandSendMessage, c.sendMessage)
+	c.commandRegistry.Register(request.CommandCloseConversation, c.closeConversation)
+	c.commandRegistry.Register(request.CommandTyping, c.sendTyping)
+	c.commandRegistry.Register(request.CommandAttachment, c.sendAttachment)
+	c.commandRegistry.Register(request.CommandResubscribe, c.resubscribe)
+}
+
+func (c *QueueHandlerImpl) decodeEnvelope(ctx context.Context, message *library.Message) (request.Envelope, error) {
+	envelope, err := c.serializer.Deserialize(ctx, message.Topic, message.Value)
+	if err != nil {
+		return envelope, fmt.Errorf("%w: failed to decode message: %w", service.ErrInvalidMessage, err)
+	}
+
+	if envelope.Type == "" {
+		return request.Envelope{
+			Type:      request.CommandCreateConversation,
+			Version:   request.EnvelopeVersion,
+			Id:        library.GetHeader(message, library.HeaderRequestId),
+			Timestamp: message.Timestamp,
+			Payload:   message.Value,
+		}, nil
+	}
+
+	if envelope.Version > request.EnvelopeVersion {
+		return envelope, fmt.Errorf("%w: unsupported envelope version %d", service.ErrInvalidMessage, envelope.Version)
+	}
+
+	return envelope, nil
+}
+
+func (c *QueueHandlerImpl) decodePayload(envelope request.Envelope, payload interface{}) error {
+	if err := json.Unmarshal(envelope.Payload, payload); err != nil {
+		return fmt.Errorf("%w: failed to decode %s payload: %w", service.ErrInvalidMessage, envelope.Type, err)
+	}
+
+	if err := c.validate.Struct(payload); err != nil {
+		return fmt.Errorf("%w: invalid %s payload: %w", service.ErrInvalidMessage, envelope.Type, err)
+	}
+
+	return nil
+}
+
+func (c *QueueHandlerImpl) createConversation(ctx context.Context, envelope request.Envelope, message *library.Message) error {
+	var req request.CreateConversationRequest
+	result, err := c.handleCreateConversation(ctx, envelope, message, &req)
+
+	c.reply(ctx, message, req, result.Body, err)
+	c.publishResult(ctx, message, envelope, req, result, err)
+
+	return err
+}
+
+func (c *QueueHandlerImpl) handleCreateConversation(ctx context.Context, envelope request.Envelope, message *library.Message, req *request.CreateConversationRequest) (service.ConsumeResult, error) {
+	if err := json.Unmarshal(envelope.Payload, req); err != nil {
+		return service.ConsumeResult{}, fmt.Errorf("%w: failed to decode message: %w", service.ErrInvalidMessage, err)
+	}
+
+	if err := c.fieldCipher.DecryptFields(req); err != nil {
+		return service.ConsumeResult{}, fmt.Errorf("%w: failed to decrypt message: %w", service.ErrInvalidMessage, err)
+	}
+
+	return c.conversationService.CreateConversationConsumer(ctx, *req, int(message.Partition))
+}
+
+func (c *QueueHandlerImpl) sendMessage(ctx context.Context, envelope request.Envelope, message *library.Message) error {
+	var req request.SendMessageRequest
+	if err := c.decodePayload(envelope, &req); err != nil {
+		return err
+	}
+
+	return c.messagingService.SendMessage(ctx, req, int(message.Partition))
+}
+
+func (c *QueueHandlerImpl) closeConversation(ctx context.Context, envelope request.Envelope, message *library.Message) error {
+	var req request.CloseConversationRequest
+	if err := c.decodePayload(envelope, &req); err != nil {
+		return err
+	}
+
+	return c.messagingService.CloseConversation(ctx, req, int(message.Partition))
+}
+
+func (c *QueueHandlerImpl) sendTyping(ctx context.Context, envelope request.Envelope, message *library.Message) error {
+	var req request.TypingRequest
+	if err := c.decodePayload(envelope, &req); err != nil {
+		return err
+	}
+
+	return c.messagingService.SendTyping(ctx, req, int(message.Partition))
+}
+
+func (c *QueueHandlerImpl) sendAttachment(ctx context.Context, envelope request.Envelope, message *library.Message) error {
+	var req request.AttachmentRequest
+	if err := c.decodePayload(envelope, &req); err != nil {
+		return err
+	}
+
+	return c.messagingService.SendAttachment(ctx, req, int(message.Partition))
+}
+
+func (c *QueueHandlerImpl) resubscribe(ctx context.Context, envelope request.Envelope, message *library.Message) error {
+	var req request.ResubscribeRequest
+	if err := c.decodePayload(envelope, &req); err != nil {
+		return err
+	}
+
+	if req.Partition != int(message.Partition) {
+		return fmt.Errorf("%w: resubscribe for partition %d received on partition %d", service.ErrInvalidMessage, req.Partition, message.Partition)
+	}
+
+	c.subscriptionService.Resubscribe(ctx, req.Partition)
+
+	return nil
+}
@@ -1,23 +1,33 @@
 package handler
 
 import (
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"salesforce-sse-worker/configs"
 	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/response"
 	"salesforce-sse-worker/internal/service"
+	"time"
 )
 
 type ConversationHandler interface {
 	GenerateToken(e echo.Context) error
 	CreateConversation(e echo.Context) error
+	SendMessage(e echo.Context) error
+	CloseConversation(e echo.Context) error
+	SendTyping(e echo.Context) error
+	SendAttachment(e echo.Context) error
 }
 
 type ConversationHandlerImpl struct {
+	queueConfig         configs.QueueConfig
 	conversationService service.ConversationService
+	webhookService      service.WebhookService
 }
 
-func NewConversationHandler(conversationService service.ConversationService) ConversationHandler {
-	return &ConversationHandlerImpl{conversationService: conversationService}
+func NewConversationHandler(queueConfig configs.QueueConfig, conversationService service.ConversationService, webhookService service.WebhookService) ConversationHandler {
+	return &ConversationHandlerImpl{queueConfig: queueConfig, conversationService: conversationService, webhookService: webhookService}
 }
 
 func (m *ConversationHandlerImpl) GenerateToken(e echo.Context) error {
@@ -52,10 +62,110 @@ func (m *ConversationHandlerImpl) CreateConversation(e echo.Context) error {
 		return e.JSON(400, map[string]string{"error": "Validation failed", "details": err.Error()})
 	}
 
-	resp, err := m.conversationService.CreateConversationProducer(e.Request().Context(), req)
+	if req.CallbackUrl != "" {
+		if err := m.webhookService.ValidateCallbackUrl(req.CallbackUrl); err != nil {
+			return e.JSON(400, map[string]string{"error": "Invalid callback url", "details": err.Error()})
+		}
+	}
+
+	if e.QueryParam("wait") != "" {
+		return m.createConversationAndWait(e, req)
+	}
+
+	requestId, err := m.conversationService.CreateConversationProducer(e.Request().Context(), req)
 	if err != nil {
 		return e.JSON(500, map[string]string{"error": err.Error()})
 	}
 
-	return e.JSON(200, resp)
+	e.Response().Header().Set(echo.HeaderXRequestID, requestId)
+
+	return e.JSON(200, "Message successfully queued")
+}
+
+func (m *ConversationHandlerImpl) createConversationAndWait(e echo.Context, req request.CreateConversationRequest) error {
+	wait, err := time.ParseDuration(e.QueryParam("wait"))
+	if err != nil || wait <= 0 {
+		return e.JSON(400, map[string]string{"error": "Invalid wait duration"})
+	}
+
+	if maxWait := time.Duration(m.queueConfig.ReplyMaxWait) * time.Millisecond; wait > maxWait {
+		wait = maxWait
+	}
+
+	reply, queued, err := m.conversationService.CreateConversationAndWait(e.Request().Context(), req, wait)
+	if errors.Is(err, service.ErrReplyListenerDisabled) {
+		return e.JSON(400, map[string]string{"error": "Synchronous create is not available", "details": err.Error()})
+	}
+	if err != nil {
+		return e.JSON(500, map[string]string{"error": err.Error()})
+	}
+
+	e.Response().Header().Set(echo.HeaderXRequestID, queued.RequestId)
+
+	if reply == nil {
+		return e.JSON(202, queued)
+	}
+
+	if reply.Status == response.ConversationStatusFailed {
+		return e.JSON(502, reply)
+	}
+
+	return e.JSON(200, reply)
+}
+
+func (m *ConversationHandlerImpl) SendMessage(e echo.Context) error {
+	var req request.SendMessageRequest
+
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(400, map[string]string{"error": "Invalid request body"})
+	}
+	req.ConversationId = e.Param("conversationId")
+
+	return m.produceCommand(e, request.CommandSendMessage, req.ConversationId, req)
+}
+
+func (m *ConversationHandlerImpl) CloseConversation(e echo.Context) error {
+	var req request.CloseConversationRequest
+
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(400, map[string]string{"error": "Invalid request body"})
+	}
+	req.ConversationId = e.Param("conversationId")
+
+	return m.produceCommand(e, request.CommandCloseConversation, req.ConversationId, req)
+}
+
+func (m *ConversationHandlerImpl) SendTyping(e echo.Context) error {
+	var req request.TypingRequest
+
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(400, map[string]string{"error": "Invalid request body"})
+	}
+	req.ConversationId = e.Param("conversationId")
+
+	return m.produceCommand(e, request.CommandTyping, req.ConversationId, req)
+}
+
+func (m *ConversationHandlerImpl) SendAttachment(e echo.Context) error {
+	var req request.AttachmentRequest
+
+	if err := e.Bind(&req); err != nil {
+		return e.JSON(400, map[string]string{"error": "Invalid request body"})
+	}
+	req.ConversationId = e.Param("conversationId")
+
+	return m.produceCommand(e, request.CommandAttachment, req.ConversationId, req)
+}
+
+func (m *ConversationHandlerImpl) produceCommand(e echo.Context, commandType string, conversationId string, req interface{}) error {
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return e.JSON(400, map[string]string{"error": "Validation failed", "details": err.Error()})
+	}
+
+	if err := m.conversationService.ProduceCommand(e.Request().Context(), commandType, conversationId, req); err != nil {
+		return e.JSON(500, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(200, "Message successfully queued")
 }
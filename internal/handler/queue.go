@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/service"
+	"strconv"
+)
+
+type (
+	QueueHandlerImpl struct {
+		queueConfig         configs.QueueConfig
+		conversationService service.ConversationService
+		messagingService    service.MessagingService
+		queueProducer       library.QueueProducer
+		serializer          library.Serializer
+		fieldCipher         library.FieldCipher
+		tokenCache          service.TokenCache
+		subscriptionService service.SubscriptionService
+		commandRegistry     CommandRegistry
+		validate            *validator.Validate
+	}
+)
+
+func NewQueueHandler(queueConfig configs.QueueConfig, conversationService service.ConversationService, messagingService service.MessagingService, queueProducer library.QueueProducer, serializer library.Serializer, fieldCipher library.FieldCipher, tokenCache service.TokenCache, subscriptionService service.SubscriptionService, commandRegistry CommandRegistry) library.QueueHandler {
+	handler := &QueueHandlerImpl{
+		queueConfig:         queueConfig,
+		conversationService: conversationService,
+		messagingService:    messagingService,
+		queueProducer:       queueProducer,
+		serializer:          serializer,
+		fieldCipher:         fieldCipher,
+		tokenCache:          tokenCache,
+		subscriptionService: subscriptionService,
+		commandRegistry:     commandRegistry,
+		validate:            validator.New(),
+	}
+	handler.registerCommands()
+
+	return handler
+}
+
+func (c *QueueHandlerImpl) Setup(ctx context.Context, claims map[string][]int32) error {
+	for _, partitions := range claims {
+		c.tokenCache.Load(ctx, toInts(partitions))
+
+		for _, partition := range partitions {
+			slog.InfoContext(ctx, "SSE Subscribed", slog.Any("partition", partition))
+			c.subscriptionService.Subscribe(ctx, int(partition))
+		}
+	}
+
+	return nil
+}
+
+func (c *QueueHandlerImpl) Cleanup(ctx context.Context, claims map[string][]int32) error {
+	for _, partitions := range claims {
+		for _, partition := range partitions {
+			slog.InfoContext(ctx, "SSE Revoked", slog.Any("partition", partition))
+		}
+
+		c.subscriptionService.Unsubscribe(ctx, toInts(partitions)...)
+		c.tokenCache.Invalidate(toInts(partitions)...)
+	}
+
+	return nil
+}
+
+func (c *QueueHandlerImpl) Handle(ctx context.Context, message *library.Message) error {
+	slog.InfoContext(ctx, "Queue message consumed",
+		slog.String("topic", message.Topic),
+		slog.Int("partition", int(message.Partition)),
+		slog.Any("value", message.Value),
+	)
+
+	envelope, err := c.decodeEnvelope(ctx, message)
+	if err != nil {
+		return c.deadLetter(ctx, message, err)
+	}
+
+	command, ok := c.commandRegistry.Lookup(envelope.Type)
+	if !ok {
+		return c.deadLetter(ctx, message, fmt.Errorf("%w: unknown command type %q", service.ErrInvalidMessage, envelope.Type))
+	}
+
+	if err := command(ctx, envelope, message); err != nil {
+		if errors.Is(err, service.ErrInvalidMessage) {
+			return c.deadLetter(ctx, message, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (c *QueueHandlerImpl) deadLetter(ctx context.Context, message *library.Message, reason error) error {
+	if c.queueConfig.DlqTopic == "" {
+		slog.ErrorContext(ctx, "Message skipped, no DLQ configured",
+			slog.String("topic", message.Topic),
+			slog.Int("partition", int(message.Partition)),
+			slog.Int64("offset", message.Offset),
+			slog.Any("reason", reason),
+		)
+
+		return nil
+	}
+
+	headers := []library.MessageHeader{
+		{Key: library.HeaderDlqReason, Value: []byte(reason.Error())},
+		{Key: library.HeaderDlqOriginalTopic, Value: []byte(message.Topic)},
+		{Key: library.HeaderDlqOriginalPartition, Value: []byte(strconv.Itoa(int(message.Partition)))},
+		{Key: library.HeaderDlqOriginalOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+	}
+	headers = append(headers, message.Headers...)
+
+	if _, _, err := c.queueProducer.Produce(ctx, &library.Message{
+		Topic:   c.queueConfig.DlqTopic,
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	}); err != nil {
+		return fmt.Errorf("failed to dead letter message: %w", errors.Join(reason, err))
+	}
+
+	slog.WarnContext(ctx, "Message routed to DLQ",
+		slog.String("topic", message.Topic),
+		slog.Int("partition", int(message.Partition)),
+		slog.Int64("offset", message.Offset),
+		slog.Any("reason", reason),
+	)
+
+	return nil
+}
+
+func (c *QueueHandlerImpl) publishResult(ctx context.Context, message *library.Message, envelope request.Envelope, req request.CreateConversationRequest, result service.ConsumeResult, handleErr error) {
+	if c.queueConfig.ResultTopic == "" {
+		return
+	}
+
+	requestId := envelope.Id
+	event := c.conversationService.NewConversationEvent(requestId, req, int(message.Partition), message.Offset, result, handleErr)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal result event", slog.Any("error", err))
+		return
+	}
+
+	if _, _, err := c.queueProducer.Produce(ctx, &library.Message{
+		Topic: c.queueConfig.ResultTopic,
+		Key:   []byte(req.ConversationId),
+		Value: payload,
+		Headers: []library.MessageHeader{
+			{Key: library.HeaderRequestId, Value: []byte(requestId)},
+		},
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to produce result event", slog.String("requestId", requestId), slog.Any("error", err))
+	}
+}
+
+func (c *QueueHandlerImpl) reply(ctx context.Context, message *library.Message, req request.CreateConversationRequest, body []byte, handleErr error) {
+	replyTopic := library.GetHeader(message, library.HeaderReplyTopic)
+	correlationId := library.GetHeader(message, library.HeaderCorrelationId)
+	if replyTopic == "" || correlationId == "" {
+		return
+	}
+
+	payload, err := json.Marshal(c.conversationService.NewConversationReply(correlationId, req, body, handleErr))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to marshal reply", slog.Any("error", err))
+		return
+	}
+
+	if _, _, err := c.queueProducer.Produce(ctx, &library.Message{
+		Topic: replyTopic,
+		Key:   []byte(correlationId),
+		Value: payload,
+		Headers: []library.MessageHeader{
+			{Key: library.HeaderCorrelationId, Value: []byte(correlationId)},
+		},
+	}); err != nil {
+		slog.ErrorContext(ctx, "Failed to produce reply", slog.String("correlationId", correlationId), slog.Any("error", err))
+	}
+}
+
+func toInts(partitions []int32) []int {
+	result := make([]int, len(partitions))
+	for i, partition := range partitions {
+		result[i] = int(partition)
+	}
+
+	return result
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"salesforce-sse-worker/internal/service"
+)
+
+type WebhookHandler interface {
+	FindDeliveries(e echo.Context) error
+	FindDelivery(e echo.Context) error
+	Redeliver(e echo.Context) error
+}
+
+type WebhookHandlerImpl struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) WebhookHandler {
+	return &WebhookHandlerImpl{webhookService: webhookService}
+}
+
+func (w *WebhookHandlerImpl) FindDeliveries(e echo.Context) error {
+	resp, err := w.webhookService.FindDeliveries(e.Request().Context(), e.QueryParam("status"))
+	if err != nil {
+		return e.JSON(500, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(200, resp)
+}
+
+func (w *WebhookHandlerImpl) FindDelivery(e echo.Context) error {
+	resp, err := w.webhookService.FindDelivery(e.Request().Context(), e.Param("id"))
+	if err != nil {
+		return w.error(e, err)
+	}
+
+	if resp == nil {
+		return e.JSON(404, map[string]string{"error": "Delivery not found"})
+	}
+
+	return e.JSON(200, resp)
+}
+
+func (w *WebhookHandlerImpl) Redeliver(e echo.Context) error {
+	resp, err := w.webhookService.Redeliver(e.Request().Context(), e.Param("id"))
+	if err != nil {
+		return w.error(e, err)
+	}
+
+	if resp == nil {
+		return e.JSON(404, map[string]string{"error": "Delivery not found"})
+	}
+
+	return e.JSON(200, resp)
+}
+
+func (w *WebhookHandlerImpl) error(e echo.Context, err error) error {
+	if errors.Is(err, service.ErrInvalidMessage) {
+		return e.JSON(400, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(500, map[string]string{"error": err.Error()})
+}
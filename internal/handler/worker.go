@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"salesforce-sse-worker/internal/service"
+	"strconv"
+)
+
+type WorkerHandler interface {
+	Status(e echo.Context) error
+	Pause(e echo.Context) error
+	Resume(e echo.Context) error
+}
+
+type WorkerHandlerImpl struct {
+	workerService service.WorkerService
+}
+
+func NewWorkerHandler(workerService service.WorkerService) WorkerHandler {
+	return &WorkerHandlerImpl{workerService: workerService}
+}
+
+func (w *WorkerHandlerImpl) Status(e echo.Context) error {
+	return e.JSON(200, w.workerService.Status(e.Request().Context()))
+}
+
+func (w *WorkerHandlerImpl) Pause(e echo.Context) error {
+	partition, err := strconv.Atoi(e.Param("partition"))
+	if err != nil || partition < 0 {
+		return e.JSON(400, map[string]string{"error": "Invalid partition"})
+	}
+
+	if err := w.workerService.Pause(e.Request().Context(), partition); err != nil {
+		return w.error(e, err)
+	}
+
+	return e.JSON(200, "Partition paused")
+}
+
+func (w *WorkerHandlerImpl) Resume(e echo.Context) error {
+	partition, err := strconv.Atoi(e.Param("partition"))
+	if err != nil || partition < 0 {
+		return e.JSON(400, map[string]string{"error": "Invalid partition"})
+	}
+
+	if err := w.workerService.Resume(e.Request().Context(), partition); err != nil {
+		return w.error(e, err)
+	}
+
+	return e.JSON(200, "Partition resumed")
+}
+
+func (w *WorkerHandlerImpl) error(e echo.Context, err error) error {
+	if errors.Is(err, service.ErrPartitionNotFound) {
+		return e.JSON(404, map[string]string{"error": err.Error()})
+	}
+
+	return e.JSON(500, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,150 @@
+package library
+
+import (
+	"fmt"
+	"github.com/hamba/avro/v2"
+	"time"
+)
+
+type (
+	avroCodec struct{}
+
+	avroDescriptor struct {
+		schema avro.Schema
+	}
+)
+
+func (a avroCodec) schemaType() string {
+	return SchemaTypeAvro
+}
+
+func (a avroCodec) schema(record schemaRecord) (string, error) {
+	schema, err := avroRecordSchema(record)
+	if err != nil {
+		return "", err
+	}
+
+	return schema.String(), nil
+}
+
+func (a avroCodec) parse(schema string, messageIndexes []int) (schemaDescriptor, error) {
+	parsed, err := avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse avro schema: %w", err)
+	}
+
+	if parsed.Type() != avro.Record {
+		return nil, fmt.Errorf("avro schema is a %s, not a record", parsed.Type())
+	}
+
+	return &avroDescriptor{schema: parsed}, nil
+}
+
+func (a avroCodec) readMessageIndexes(data []byte) ([]int, []byte, error) {
+	return nil, data, nil
+}
+
+func (d *avroDescriptor) encode(value map[string]interface{}) ([]byte, error) {
+	encoded, err := avroValue(d.schema, "", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return avro.Marshal(d.schema, encoded)
+}
+
+func (d *avroDescriptor) decode(data []byte) (map[string]interface{}, error) {
+	var value map[string]interface{}
+	if err := avro.Unmarshal(d.schema, data, &value); err != nil {
+		return nil, err
+	}
+
+	return fromAvroValue(value).(map[string]interface{}), nil
+}
+
+func avroRecordSchema(record schemaRecord) (*avro.RecordSchema, error) {
+	fields := []*avro.Field{}
+	for _, field := range record.Fields {
+		var fieldSchema avro.Schema
+		switch {
+		case field.Record != nil:
+			nested, err := avroRecordSchema(*field.Record)
+			if err != nil {
+				return nil, err
+			}
+			fieldSchema = nested
+		case field.LogicalType != "":
+			fieldSchema = avro.NewPrimitiveSchema(avro.Type(field.Type), avro.NewPrimitiveLogicalSchema(avro.LogicalType(field.LogicalType)))
+		default:
+			fieldSchema = avro.NewPrimitiveSchema(avro.Type(field.Type), nil)
+		}
+
+		avroField, err := avro.NewField(field.Name, fieldSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build avro field %s: %w", field.Name, err)
+		}
+		fields = append(fields, avroField)
+	}
+
+	return avro.NewRecordSchema(record.Name, schemaNamespace, fields)
+}
+
+func avroValue(schema avro.Schema, name string, value interface{}) (interface{}, error) {
+	switch s := schema.(type) {
+	case *avro.RecordSchema:
+		record, err := schemaMap(name, value)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded := map[string]interface{}{}
+		for _, field := range s.Fields() {
+			if encoded[field.Name()], err = avroValue(field.Type(), field.Name(), record[field.Name()]); err != nil {
+				return nil, err
+			}
+		}
+
+		return encoded, nil
+	case *avro.PrimitiveSchema:
+		switch s.Type() {
+		case avro.String:
+			return schemaString(name, value)
+		case avro.Bytes:
+			return schemaBytes(name, value)
+		case avro.Int:
+			v, err := schemaLong(name, value)
+			return int(v), err
+		case avro.Long:
+			return schemaLong(name, value)
+		case avro.Boolean:
+			return schemaBoolean(name, value)
+		}
+	}
+
+	return value, nil
+}
+
+func fromAvroValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		decoded := make(map[string]interface{}, len(v))
+		for name, field := range v {
+			decoded[name] = fromAvroValue(field)
+		}
+		return decoded
+	case []interface{}:
+		decoded := make([]interface{}, len(v))
+		for i, item := range v {
+			decoded[i] = fromAvroValue(item)
+		}
+		return decoded
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case time.Time:
+		return v.UnixMilli()
+	default:
+		return value
+	}
+}
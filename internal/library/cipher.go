@@ -0,0 +1,152 @@
+package library
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"salesforce-sse-worker/configs"
+)
+
+const dataKeySize = 32
+
+var ErrEncryptionDisabled = errors.New("encryption is not configured")
+
+type (
+	EncryptedValue struct {
+		KeyId      string
+		DataKey    []byte
+		Ciphertext []byte
+	}
+
+	Cipher interface {
+		Enabled() bool
+		ActiveKeyId() string
+		Encrypt(plaintext []byte) (EncryptedValue, error)
+		Decrypt(value EncryptedValue) ([]byte, error)
+	}
+
+	AESCipherImpl struct {
+		activeKeyId string
+		keys        map[string]cipher.AEAD
+	}
+)
+
+func NewCipher(cfg configs.EncryptionConfig) (Cipher, error) {
+	return NewAESCipher(cfg.ActiveKeyId, cfg.Keys)
+}
+
+func NewAESCipher(activeKeyId string, encodedKeys map[string]string) (Cipher, error) {
+	keys := make(map[string]cipher.AEAD, len(encodedKeys))
+	for keyId, encodedKey := range encodedKeys {
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %s: %w", keyId, err)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", keyId, err)
+		}
+
+		keys[keyId] = aead
+	}
+
+	if activeKeyId != "" {
+		if _, ok := keys[activeKeyId]; !ok {
+			return nil, fmt.Errorf("active key %s is not configured", activeKeyId)
+		}
+	}
+
+	return &AESCipherImpl{activeKeyId: activeKeyId, keys: keys}, nil
+}
+
+func (a *AESCipherImpl) Enabled() bool {
+	return a.activeKeyId != ""
+}
+
+func (a *AESCipherImpl) ActiveKeyId() string {
+	return a.activeKeyId
+}
+
+func (a *AESCipherImpl) Encrypt(plaintext []byte) (EncryptedValue, error) {
+	if !a.Enabled() {
+		return EncryptedValue{}, ErrEncryptionDisabled
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return EncryptedValue{}, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return EncryptedValue{}, err
+	}
+
+	ciphertext, err := seal(dataAEAD, plaintext)
+	if err != nil {
+		return EncryptedValue{}, err
+	}
+
+	wrappedKey, err := seal(a.keys[a.activeKeyId], dataKey)
+	if err != nil {
+		return EncryptedValue{}, err
+	}
+
+	return EncryptedValue{KeyId: a.activeKeyId, DataKey: wrappedKey, Ciphertext: ciphertext}, nil
+}
+
+func (a *AESCipherImpl) Decrypt(value EncryptedValue) ([]byte, error) {
+	keyAEAD, ok := a.keys[value.KeyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %s", value.KeyId)
+	}
+
+	dataKey, err := open(keyAEAD, value.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataAEAD, value.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, sealed, nil)
+}
@@ -0,0 +1,256 @@
+package library
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"salesforce-sse-worker/internal/request"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	schemaNamespace = "salesforce.sse.worker"
+
+	schemaTypeString  = "string"
+	schemaTypeBytes   = "bytes"
+	schemaTypeInt     = "int"
+	schemaTypeLong    = "long"
+	schemaTypeBoolean = "boolean"
+	schemaTypeRecord  = "record"
+
+	schemaLogicalTimestampMillis = "timestamp-millis"
+
+	protobufMinFieldNumber      = 1
+	protobufMaxFieldNumber      = 1<<29 - 1
+	protobufFirstReservedNumber = 19000
+	protobufLastReservedNumber  = 19999
+)
+
+var commandPayloads = map[string]interface{}{
+	request.CommandCreateConversation: request.CreateConversationRequest{},
+	request.CommandSendMessage:        request.SendMessageRequest{},
+	request.CommandCloseConversation:  request.CloseConversationRequest{},
+	request.CommandTyping:             request.TypingRequest{},
+	request.CommandAttachment:         request.AttachmentRequest{},
+	request.CommandResubscribe:        request.ResubscribeRequest{},
+}
+
+type (
+	schemaRecord struct {
+		Name   string
+		Fields []schemaField
+	}
+
+	schemaField struct {
+		Name        string
+		Number      int
+		Type        string
+		LogicalType string
+		Record      *schemaRecord
+	}
+)
+
+func newCommandSchemas() (map[string]schemaRecord, error) {
+	schemas := map[string]schemaRecord{}
+	for commandType, payload := range commandPayloads {
+		payloadRecord, err := schemaRecordOf(reflect.TypeOf(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s schema: %w", commandType, err)
+		}
+
+		schemas[commandType] = schemaRecord{
+			Name: commandSchemaName(commandType),
+			Fields: []schemaField{
+				{Name: "type", Number: 1, Type: schemaTypeString},
+				{Name: "version", Number: 2, Type: schemaTypeInt},
+				{Name: "id", Number: 3, Type: schemaTypeString},
+				{Name: "timestamp", Number: 4, Type: schemaTypeLong, LogicalType: schemaLogicalTimestampMillis},
+				{Name: "payload", Number: 5, Type: schemaTypeRecord, Record: &payloadRecord},
+			},
+		}
+	}
+
+	return schemas, nil
+}
+
+func commandSchemaName(commandType string) string {
+	var name strings.Builder
+	for _, part := range strings.FieldsFunc(commandType, func(r rune) bool { return r == '.' || r == '-' }) {
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+
+	return name.String()
+}
+
+func schemaRecordOf(t reflect.Type) (schemaRecord, error) {
+	record := schemaRecord{Name: t.Name()}
+	numbers := map[int]string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		number, err := schemaFieldNumber(structField)
+		if err != nil {
+			return record, fmt.Errorf("field %s: %w", name, err)
+		}
+		if other, ok := numbers[number]; ok {
+			return record, fmt.Errorf("field %s: number %d is already used by field %s", name, number, other)
+		}
+		numbers[number] = name
+
+		field := schemaField{Name: name, Number: number}
+		switch {
+		case structField.Type.Kind() == reflect.String:
+			field.Type = schemaTypeString
+		case structField.Type.Kind() == reflect.Bool:
+			field.Type = schemaTypeBoolean
+		case structField.Type.Kind() == reflect.Int:
+			field.Type = schemaTypeLong
+		case structField.Type == reflect.TypeOf([]byte(nil)):
+			field.Type = schemaTypeBytes
+		case structField.Type.Kind() == reflect.Struct:
+			nested, err := schemaRecordOf(structField.Type)
+			if err != nil {
+				return record, err
+			}
+			field.Type, field.Record = schemaTypeRecord, &nested
+		default:
+			return record, fmt.Errorf("unsupported type %s for field %s", structField.Type, name)
+		}
+
+		record.Fields = append(record.Fields, field)
+	}
+
+	return record, nil
+}
+
+func schemaFieldNumber(structField reflect.StructField) (int, error) {
+	tag, ok := structField.Tag.Lookup("protobuf")
+	if !ok {
+		return 0, fmt.Errorf("missing protobuf field number tag")
+	}
+
+	number, err := strconv.Atoi(tag)
+	if err != nil || number < protobufMinFieldNumber || number > protobufMaxFieldNumber || (number >= protobufFirstReservedNumber && number <= protobufLastReservedNumber) {
+		return 0, fmt.Errorf("invalid protobuf field number %q", tag)
+	}
+
+	return number, nil
+}
+
+func envelopeToValue(envelope request.Envelope) (map[string]interface{}, error) {
+	var payload map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(envelope.Payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", envelope.Type, err)
+	}
+
+	return map[string]interface{}{
+		"type":      envelope.Type,
+		"version":   int64(envelope.Version),
+		"id":        envelope.Id,
+		"timestamp": envelope.Timestamp.UnixMilli(),
+		"payload":   payload,
+	}, nil
+}
+
+func envelopeFromValue(value map[string]interface{}) (request.Envelope, error) {
+	payload, err := json.Marshal(value["payload"])
+	if err != nil {
+		return request.Envelope{}, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	envelope := request.Envelope{Payload: payload}
+	envelope.Type, _ = value["type"].(string)
+	envelope.Id, _ = value["id"].(string)
+
+	version, _ := value["version"].(int64)
+	envelope.Version = int(version)
+
+	if timestamp, ok := value["timestamp"].(int64); ok {
+		envelope.Timestamp = time.UnixMilli(timestamp)
+	}
+
+	return envelope, nil
+}
+
+func schemaString(name string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("field %s: expected string, got %T", name, value)
+	}
+}
+
+func schemaBytes(name string, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("field %s: expected bytes, got %T", name, value)
+	}
+}
+
+func schemaLong(name string, value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("field %s: %w", name, err)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("field %s: expected number, got %T", name, value)
+	}
+}
+
+func schemaBoolean(name string, value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("field %s: expected boolean, got %T", name, value)
+	}
+}
+
+func schemaMap(name string, value interface{}) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case map[string]interface{}:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("field %s: expected record, got %T", name, value)
+	}
+}
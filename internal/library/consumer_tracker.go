@@ -0,0 +1,172 @@
+package library
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type (
+	ConsumerStatus struct {
+		Generation int32
+		Partitions []PartitionStatus
+	}
+
+	PartitionStatus struct {
+		Topic      string
+		Partition  int32
+		InFlight   int64
+		LastOffset int64
+		Paused     bool
+	}
+
+	consumerTracker struct {
+		mu         sync.Mutex
+		generation int32
+		partitions map[string]map[int32]*PartitionStatus
+		paused     map[string]map[int32]bool
+		changed    chan struct{}
+	}
+)
+
+func newConsumerTracker() *consumerTracker {
+	return &consumerTracker{
+		partitions: map[string]map[int32]*PartitionStatus{},
+		paused:     map[string]map[int32]bool{},
+		changed:    make(chan struct{}),
+	}
+}
+
+func (t *consumerTracker) Status() ConsumerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := ConsumerStatus{Generation: t.generation, Partitions: []PartitionStatus{}}
+	for topic, partitions := range t.partitions {
+		for partition, partitionStatus := range partitions {
+			current := *partitionStatus
+			current.Paused = t.paused[topic][partition]
+			status.Partitions = append(status.Partitions, current)
+		}
+	}
+
+	sort.Slice(status.Partitions, func(i, j int) bool {
+		if status.Partitions[i].Topic != status.Partitions[j].Topic {
+			return status.Partitions[i].Topic < status.Partitions[j].Topic
+		}
+		return status.Partitions[i].Partition < status.Partitions[j].Partition
+	})
+
+	return status
+}
+
+func (t *consumerTracker) assign(generation int32, claims map[string][]int32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := t.partitions
+
+	t.generation = generation
+	t.partitions = map[string]map[int32]*PartitionStatus{}
+	for topic, partitions := range claims {
+		t.partitions[topic] = map[int32]*PartitionStatus{}
+		for _, partition := range partitions {
+			if partitionStatus, ok := previous[topic][partition]; ok {
+				t.partitions[topic][partition] = partitionStatus
+				continue
+			}
+			t.partitions[topic][partition] = &PartitionStatus{Topic: topic, Partition: partition, LastOffset: -1}
+		}
+	}
+
+	for topic, partitions := range t.paused {
+		for partition := range partitions {
+			if _, ok := t.partitions[topic][partition]; !ok {
+				delete(partitions, partition)
+			}
+		}
+	}
+}
+
+func (t *consumerTracker) nextGeneration(claims map[string][]int32) {
+	t.mu.Lock()
+	generation := t.generation + 1
+	t.mu.Unlock()
+
+	t.assign(generation, claims)
+}
+
+func (t *consumerTracker) begin(message *Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if partitionStatus := t.partition(message.Topic, message.Partition); partitionStatus != nil {
+		partitionStatus.InFlight++
+	}
+}
+
+func (t *consumerTracker) end(message *Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if partitionStatus := t.partition(message.Topic, message.Partition); partitionStatus != nil {
+		partitionStatus.InFlight--
+		partitionStatus.LastOffset = message.Offset
+	}
+}
+
+func (t *consumerTracker) setPaused(partitions map[string][]int32, paused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for topic, topicPartitions := range partitions {
+		if _, ok := t.paused[topic]; !ok {
+			t.paused[topic] = map[int32]bool{}
+		}
+
+		for _, partition := range topicPartitions {
+			if paused {
+				t.paused[topic][partition] = true
+			} else {
+				delete(t.paused[topic], partition)
+			}
+		}
+	}
+
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (t *consumerTracker) isPaused(topic string, partition int32) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.paused[topic][partition]
+}
+
+func (t *consumerTracker) waitResumed(ctx context.Context, topic string, partition int32) bool {
+	for {
+		t.mu.Lock()
+		paused := t.paused[topic][partition]
+		changed := t.changed
+		t.mu.Unlock()
+
+		if !paused {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+func (t *consumerTracker) partition(topic string, partition int32) *PartitionStatus {
+	if partitions, ok := t.partitions[topic]; ok {
+		return partitions[partition]
+	}
+
+	return nil
+}
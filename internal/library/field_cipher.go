@@ -0,0 +1,151 @@
+package library
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"salesforce-sse-worker/configs"
+	"strings"
+)
+
+const (
+	sensitiveTag         = "sensitive"
+	encryptedFieldPrefix = "enc:v2:"
+)
+
+type (
+	FieldCipher interface {
+		EncryptFields(v interface{}) error
+		DecryptFields(v interface{}) error
+	}
+
+	FieldCipherImpl struct {
+		enabled bool
+		cipher  Cipher
+	}
+)
+
+func NewFieldCipher(cfg configs.PayloadEncryptionConfig) (FieldCipher, error) {
+	cipher, err := NewAESCipher(cfg.ActiveKeyId, cfg.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Enabled && !cipher.Enabled() {
+		return nil, fmt.Errorf("payload encryption enabled without an active key: %w", ErrEncryptionDisabled)
+	}
+
+	return &FieldCipherImpl{enabled: cfg.Enabled, cipher: cipher}, nil
+}
+
+func (f *FieldCipherImpl) EncryptFields(v interface{}) error {
+	if !f.enabled {
+		return nil
+	}
+
+	return walkSensitiveFields(v, func(value string) (string, error) {
+		if value == "" {
+			return value, nil
+		}
+
+		encrypted, err := f.cipher.Encrypt([]byte(value))
+		if err != nil {
+			return "", err
+		}
+
+		return encryptedFieldPrefix + strings.Join([]string{
+			base64.RawURLEncoding.EncodeToString([]byte(encrypted.KeyId)),
+			base64.RawURLEncoding.EncodeToString(encrypted.DataKey),
+			base64.RawURLEncoding.EncodeToString(encrypted.Ciphertext),
+		}, "."), nil
+	})
+}
+
+func (f *FieldCipherImpl) DecryptFields(v interface{}) error {
+	if !f.enabled {
+		return nil
+	}
+
+	return walkSensitiveFields(v, func(value string) (string, error) {
+		if !strings.HasPrefix(value, encryptedFieldPrefix) {
+			return value, nil
+		}
+
+		encrypted, err := parseEncryptedField(strings.TrimPrefix(value, encryptedFieldPrefix))
+		if err != nil {
+			return "", err
+		}
+
+		plaintext, err := f.cipher.Decrypt(encrypted)
+		if err != nil {
+			return "", err
+		}
+
+		return string(plaintext), nil
+	})
+}
+
+func parseEncryptedField(value string) (EncryptedValue, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return EncryptedValue{}, errors.New("malformed encrypted field")
+	}
+
+	keyId, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return EncryptedValue{}, fmt.Errorf("failed to decode key id: %w", err)
+	}
+
+	dataKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return EncryptedValue{}, fmt.Errorf("failed to decode data key: %w", err)
+	}
+
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return EncryptedValue{}, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	return EncryptedValue{KeyId: string(keyId), DataKey: dataKey, Ciphertext: ciphertext}, nil
+}
+
+func walkSensitiveFields(v interface{}, transform func(string) (string, error)) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected pointer to struct, got %T", v)
+	}
+
+	return walkStruct(value.Elem(), transform)
+}
+
+func walkStruct(value reflect.Value, transform func(string) (string, error)) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+
+		if !structField.IsExported() {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Struct:
+			if err := walkStruct(field, transform); err != nil {
+				return err
+			}
+		case reflect.String:
+			if structField.Tag.Get(sensitiveTag) != "true" {
+				continue
+			}
+
+			transformed, err := transform(field.String())
+			if err != nil {
+				return fmt.Errorf("field %s: %w", structField.Name, err)
+			}
+
+			field.SetString(transformed)
+		}
+	}
+
+	return nil
+}
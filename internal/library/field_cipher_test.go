@@ -0,0 +1,71 @@
+package library
+
+import (
+	"encoding/base64"
+	"salesforce-sse-worker/configs"
+	"strings"
+	"testing"
+)
+
+type fieldCipherPayload struct {
+	Name   string `sensitive:"true"`
+	Origin string
+}
+
+func newTestFieldCipher(t *testing.T, enabled bool) FieldCipher {
+	t.Helper()
+
+	fieldCipher, err := NewFieldCipher(configs.PayloadEncryptionConfig{
+		Enabled: enabled,
+		EncryptionConfig: configs.EncryptionConfig{
+			ActiveKeyId: "k1",
+			Keys:        map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		},
+	})
+	if err != nil {
+		t.Fatalf("field cipher: %v", err)
+	}
+
+	return fieldCipher
+}
+
+func TestFieldCipherRoundTripsSensitiveFields(t *testing.T) {
+	fieldCipher := newTestFieldCipher(t, true)
+
+	payload := fieldCipherPayload{Name: "name", Origin: "origin"}
+	if err := fieldCipher.EncryptFields(&payload); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(payload.Name, encryptedFieldPrefix) || payload.Origin != "origin" {
+		t.Fatalf("got %+v", payload)
+	}
+
+	if err := fieldCipher.DecryptFields(&payload); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if payload.Name != "name" {
+		t.Fatalf("got %+v", payload)
+	}
+
+	payload.Name = "enc:v1:k1:a:b"
+	if err := fieldCipher.DecryptFields(&payload); err != nil || payload.Name != "enc:v1:k1:a:b" {
+		t.Fatalf("got %+v and error %v for a value without the current prefix", payload, err)
+	}
+}
+
+func TestFieldCipherLeavesFieldsAloneWhenDisabled(t *testing.T) {
+	fieldCipher := newTestFieldCipher(t, false)
+
+	payload := fieldCipherPayload{Name: encryptedFieldPrefix + "not.really.encrypted"}
+	if err := fieldCipher.DecryptFields(&payload); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if payload.Name != encryptedFieldPrefix+"not.really.encrypted" {
+		t.Fatalf("got %+v", payload)
+	}
+
+	payload.Name = "name"
+	if err := fieldCipher.EncryptFields(&payload); err != nil || payload.Name != "name" {
+		t.Fatalf("got %+v and error %v", payload, err)
+	}
+}
@@ -11,6 +11,7 @@ type (
 	HTTPClient interface {
 		Get(ctx context.Context, request request.HTTPRequest) (*http.Response, error)
 		Post(ctx context.Context, request request.HTTPRequest) (*http.Response, error)
+		Delete(ctx context.Context, request request.HTTPRequest) (*http.Response, error)
 	}
 
 	HTTPClientImpl struct {
@@ -30,6 +31,10 @@ func (h *HTTPClientImpl) Post(ctx context.Context, request request.HTTPRequest)
 	return h.do(ctx, http.MethodPost, request)
 }
 
+func (h *HTTPClientImpl) Delete(ctx context.Context, request request.HTTPRequest) (*http.Response, error) {
+	return h.do(ctx, http.MethodDelete, request)
+}
+
 func (h *HTTPClientImpl) do(ctx context.Context, method string, request request.HTTPRequest) (*http.Response, error) {
 	httpRequest, err := http.NewRequestWithContext(ctx, method, request.Path, request.Body)
 	if err != nil {
@@ -0,0 +1,183 @@
+package library
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"salesforce-sse-worker/configs"
+	"strings"
+)
+
+var ErrJWTDisabled = errors.New("JWT authentication is not configured")
+
+type (
+	JWTVerifier interface {
+		Verify(token string) (subject string, scopes []string, err error)
+	}
+
+	JWTVerifierImpl struct {
+		keys       map[string]interface{}
+		scopeClaim string
+		parser     *jwt.Parser
+	}
+
+	jsonWebKeySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	jsonWebKey struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+func NewJWTVerifier(cfg configs.AuthConfig) (JWTVerifier, error) {
+	keys := map[string]interface{}{}
+
+	if cfg.JwksFile != "" {
+		content, err := os.ReadFile(cfg.JwksFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+
+		var keySet jsonWebKeySet
+		if err := json.Unmarshal(content, &keySet); err != nil {
+			return nil, fmt.Errorf("failed to decode JWKS file: %w", err)
+		}
+
+		for _, key := range keySet.Keys {
+			publicKey, err := key.publicKey()
+			if err != nil {
+				return nil, fmt.Errorf("invalid JWK %s: %w", key.Kid, err)
+			}
+			keys[key.Kid] = publicKey
+		}
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &JWTVerifierImpl{
+		keys:       keys,
+		scopeClaim: cfg.ScopeClaim,
+		parser:     jwt.NewParser(parserOptions...),
+	}, nil
+}
+
+func (j *JWTVerifierImpl) Verify(token string) (string, []string, error) {
+	if len(j.keys) == 0 {
+		return "", nil, ErrJWTDisabled
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := j.parser.ParseWithClaims(token, claims, j.keyFunc); err != nil {
+		return "", nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return subject, scopesFromClaim(claims[j.scopeClaim]), nil
+}
+
+func (j *JWTVerifierImpl) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+func scopesFromClaim(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		scopes := make([]string, 0, len(value))
+		for _, scope := range value {
+			if s, ok := scope.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,267 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"salesforce-sse-worker/configs"
+	"time"
+)
+
+type (
+	KafkaAdminImpl struct {
+		client       sarama.Client
+		clusterAdmin sarama.ClusterAdmin
+	}
+)
+
+func NewKafkaAdmin(cfg configs.KafkaConfig, saramaCfg *sarama.Config) (QueueAdmin, error) {
+	client, err := sarama.NewClient(cfg.Brokers, saramaCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterAdmin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaAdminImpl{client: client, clusterAdmin: clusterAdmin}, nil
+}
+
+func (k *KafkaAdminImpl) PartitionCount(ctx context.Context, topic string) (int, error) {
+	if err := k.client.RefreshMetadata(topic); err != nil {
+		return 0, fmt.Errorf("failed to refresh metadata for topic %s: %w", topic, err)
+	}
+
+	partitions, err := k.client.Partitions(topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read partitions for topic %s: %w", topic, err)
+	}
+
+	return len(partitions), nil
+}
+
+func (k *KafkaAdminImpl) ConsumerGroupLag(ctx context.Context, group string, topic string) (int64, error) {
+	partitions, err := k.client.Partitions(topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read partitions for topic %s: %w", topic, err)
+	}
+
+	offsets, err := k.clusterAdmin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list offsets for group %s: %w", group, err)
+	}
+
+	var lag int64
+	for _, partition := range partitions {
+		newest, err := k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read newest offset for partition %d: %w", partition, err)
+		}
+
+		committed := int64(-1)
+		if block := offsets.GetBlock(topic, partition); block != nil {
+			committed = block.Offset
+		}
+
+		if committed < 0 {
+			if committed, err = k.client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+				return 0, fmt.Errorf("failed to read oldest offset for partition %d: %w", partition, err)
+			}
+		}
+
+		if newest > committed {
+			lag += newest - committed
+		}
+	}
+
+	return lag, nil
+}
+
+func (k *KafkaAdminImpl) ReadMessages(ctx context.Context, topic string, limit int) ([]*Message, error) {
+	partitions, err := k.client.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions for topic %s: %w", topic, err)
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(k.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	messages := []*Message{}
+	for _, partition := range partitions {
+		oldest, err := k.client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read oldest offset for partition %d: %w", partition, err)
+		}
+
+		newest, err := k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read newest offset for partition %d: %w", partition, err)
+		}
+
+		start := max(oldest, newest-int64(limit))
+		if start >= newest {
+			continue
+		}
+
+		partitionConsumer, err := consumer.ConsumePartition(topic, partition, start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to consume partition %d: %w", partition, err)
+		}
+
+		for offset := start; offset < newest; {
+			select {
+			case message := <-partitionConsumer.Messages():
+				messages = append(messages, fromConsumerMessage(message))
+				offset = message.Offset + 1
+			case <-ctx.Done():
+				partitionConsumer.AsyncClose()
+				return nil, ctx.Err()
+			}
+		}
+		partitionConsumer.AsyncClose()
+	}
+
+	return messages, nil
+}
+
+func (k *KafkaAdminImpl) ResetOffsets(ctx context.Context, group string, topic string, timestamp time.Time) error {
+	if err := k.requireInactiveGroup(group); err != nil {
+		return err
+	}
+
+	partitions, err := k.client.Partitions(topic)
+	if err != nil {
+		return fmt.Errorf("failed to read partitions for topic %s: %w", topic, err)
+	}
+
+	offsets := map[int32]int64{}
+	for _, partition := range partitions {
+		offset, err := k.client.GetOffset(topic, partition, timestamp.UnixMilli())
+		if err != nil {
+			return fmt.Errorf("failed to find offset for partition %d: %w", partition, err)
+		}
+
+		if offset < 0 {
+			if offset, err = k.client.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+				return fmt.Errorf("failed to read newest offset for partition %d: %w", partition, err)
+			}
+		}
+
+		offsets[partition] = offset
+	}
+
+	return k.commitOffsets(group, topic, offsets)
+}
+
+func (k *KafkaAdminImpl) CommittedOffsets(ctx context.Context, group string, topic string) (map[int32]int64, error) {
+	partitions, err := k.client.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions for topic %s: %w", topic, err)
+	}
+
+	response, err := k.clusterAdmin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets for group %s: %w", group, err)
+	}
+
+	offsets := map[int32]int64{}
+	for _, partition := range partitions {
+		block := response.GetBlock(topic, partition)
+		if block == nil || block.Offset < 0 {
+			continue
+		}
+		if !errors.Is(block.Err, sarama.ErrNoError) {
+			return nil, fmt.Errorf("failed to read offset for partition %d: %w", partition, block.Err)
+		}
+
+		offsets[partition] = block.Offset
+	}
+
+	return offsets, nil
+}
+
+func (k *KafkaAdminImpl) CommitOffsets(ctx context.Context, group string, topic string, offsets map[int32]int64) error {
+	if err := k.requireInactiveGroup(group); err != nil {
+		return err
+	}
+
+	return k.commitOffsets(group, topic, offsets)
+}
+
+func (k *KafkaAdminImpl) requireInactiveGroup(group string) error {
+	descriptions, err := k.clusterAdmin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return fmt.Errorf("failed to describe group %s: %w", group, err)
+	}
+
+	for _, description := range descriptions {
+		if !errors.Is(description.Err, sarama.ErrNoError) {
+			return fmt.Errorf("failed to describe group %s: %w", group, description.Err)
+		}
+
+		if description.State != "Empty" && description.State != "Dead" {
+			return fmt.Errorf("group %s is %s with %d members, stop its consumers first", group, description.State, len(description.Members))
+		}
+	}
+
+	return nil
+}
+
+func (k *KafkaAdminImpl) commitOffsets(group string, topic string, offsets map[int32]int64) error {
+	offsetManager, err := sarama.NewOffsetManagerFromClient(group, k.client)
+	if err != nil {
+		return fmt.Errorf("failed to create offset manager for group %s: %w", group, err)
+	}
+
+	partitionOffsetManagers := make([]sarama.PartitionOffsetManager, 0, len(offsets))
+	for partition, offset := range offsets {
+		partitionOffsetManager, err := offsetManager.ManagePartition(topic, partition)
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to manage offsets for partition %d: %w", partition, err), offsetManager.Close())
+		}
+		partitionOffsetManager.ResetOffset(offset, "")
+		partitionOffsetManager.AsyncClose()
+
+		partitionOffsetManagers = append(partitionOffsetManagers, partitionOffsetManager)
+	}
+
+	offsetManager.Commit()
+	if err := offsetManager.Close(); err != nil {
+		return fmt.Errorf("failed to close offset manager for group %s: %w", group, err)
+	}
+
+	var errs []error
+	for _, partitionOffsetManager := range partitionOffsetManagers {
+		for consumerError := range partitionOffsetManager.Errors() {
+			errs = append(errs, consumerError)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to commit offsets for group %s: %w", group, errors.Join(errs...))
+	}
+
+	partitions := make([]int32, 0, len(offsets))
+	for partition := range offsets {
+		partitions = append(partitions, partition)
+	}
+
+	committed, err := k.clusterAdmin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return fmt.Errorf("failed to verify offsets for group %s: %w", group, err)
+	}
+
+	for partition, offset := range offsets {
+		block := committed.GetBlock(topic, partition)
+		if block == nil || block.Offset != offset {
+			return fmt.Errorf("failed to commit offset %d for partition %d of group %s", offset, partition, group)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package library
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"salesforce-sse-worker/configs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKafkaAdmin(t *testing.T, state string, committedOffset int64, commitError sarama.KError) (QueueAdmin, time.Time) {
+	t.Helper()
+
+	at := time.UnixMilli(1700000000000)
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("commands", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "worker", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("worker", &sarama.GroupDescription{GroupId: "worker", State: state}),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("commands", 0, at.UnixMilli(), 42).
+			SetOffset("commands", 0, sarama.OffsetNewest, 50),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("worker", "commands", 0, committedOffset, "", sarama.ErrNoError),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t).
+			SetError("worker", "commands", 0, commitError),
+	})
+
+	saramaCfg := sarama.NewConfig()
+	saramaCfg.Consumer.Return.Errors = true
+
+	admin, err := NewKafkaAdmin(configs.KafkaConfig{Brokers: []string{broker.Addr()}}, saramaCfg)
+	if err != nil {
+		t.Fatalf("admin: %v", err)
+	}
+
+	return admin, at
+}
+
+func TestKafkaAdminResetOffsetsRequiresInactiveGroup(t *testing.T) {
+	admin, at := newTestKafkaAdmin(t, "Stable", 42, sarama.ErrNoError)
+
+	err := admin.ResetOffsets(context.Background(), "worker", "commands", at)
+	if err == nil || !strings.Contains(err.Error(), "Stable") {
+		t.Fatalf("got %v, want an error about the active group", err)
+	}
+}
+
+func TestKafkaAdminResetOffsetsCommits(t *testing.T) {
+	admin, at := newTestKafkaAdmin(t, "Empty", 42, sarama.ErrNoError)
+
+	if err := admin.ResetOffsets(context.Background(), "worker", "commands", at); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+}
+
+func TestKafkaAdminResetOffsetsReportsFailedCommit(t *testing.T) {
+	admin, at := newTestKafkaAdmin(t, "Empty", 7, sarama.ErrOffsetMetadataTooLarge)
+
+	if err := admin.ResetOffsets(context.Background(), "worker", "commands", at); err == nil {
+		t.Fatal("reset succeeded although the commit was rejected")
+	}
+}
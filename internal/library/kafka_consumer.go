@@ -5,58 +5,58 @@ import (
 	"github.com/IBM/sarama"
 	"log/slog"
 	"salesforce-sse-worker/configs"
-	"sync"
 )
 
 type (
-	KafkaHandler interface {
-		Setup(ctx context.Context, claims map[string][]int32) error
-		Cleanup(ctx context.Context, claims map[string][]int32) error
-		Handle(ctx context.Context, message *sarama.ConsumerMessage) error
-	}
-
 	KafkaConsumerHandler interface {
 		sarama.ConsumerGroupHandler
 	}
 
 	KafkaConsumerHandlerImpl struct {
-		handler KafkaHandler
-	}
-
-	KafkaConsumer interface {
-		ResetReady()
-		WaitReady()
-		Consume(ctx context.Context)
+		handler       QueueHandler
+		tracker       *consumerTracker
+		consumerGroup sarama.ConsumerGroup
 	}
 
 	KafkaConsumerImpl struct {
-		ready           chan bool
+		*consumerTracker
 		topics          []string
 		consumerGroup   sarama.ConsumerGroup
 		consumerHandler sarama.ConsumerGroupHandler
 	}
 )
 
-func NewKafkaConsumerHandler(handler KafkaHandler) KafkaConsumerHandler {
-	return &KafkaConsumerHandlerImpl{handler: handler}
+func NewKafkaConsumerHandler(handler QueueHandler, tracker *consumerTracker, consumerGroup sarama.ConsumerGroup) KafkaConsumerHandler {
+	return &KafkaConsumerHandlerImpl{handler: handler, tracker: tracker, consumerGroup: consumerGroup}
 }
 
 func (c *KafkaConsumerHandlerImpl) Setup(session sarama.ConsumerGroupSession) error {
+	c.tracker.assign(session.GenerationID(), session.Claims())
+
 	return c.handler.Setup(session.Context(), session.Claims())
 }
 
 func (c *KafkaConsumerHandlerImpl) Cleanup(session sarama.ConsumerGroupSession) error {
+	c.tracker.assign(session.GenerationID(), map[string][]int32{})
+
 	return c.handler.Cleanup(session.Context(), session.Claims())
 }
 
 func (c *KafkaConsumerHandlerImpl) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if c.tracker.isPaused(claim.Topic(), claim.Partition()) {
+		c.consumerGroup.Pause(map[string][]int32{claim.Topic(): {claim.Partition()}})
+	}
+
 	for message := range claim.Messages() {
 		sdc := map[string]string{"topic": message.Topic, "partition": string(message.Partition)}
 		slog.InfoContext(context.Background(), "Message claimed", slog.Any("sdc", sdc))
 
-		if err := c.handler.Handle(context.Background(), message); err != nil {
+		msg := fromConsumerMessage(message)
+		c.tracker.begin(msg)
+		if err := c.handler.Handle(context.Background(), msg); err != nil {
 			slog.ErrorContext(session.Context(), "Failed to handle message", slog.Any("error", err))
 		}
+		c.tracker.end(msg)
 
 		session.MarkMessage(message, "")
 	}
@@ -64,48 +64,64 @@ func (c *KafkaConsumerHandlerImpl) ConsumeClaim(session sarama.ConsumerGroupSess
 	return nil
 }
 
-func NewKafkaConsumer(cfg configs.KafkaConfig, saramaCfg *sarama.Config, handler KafkaHandler) (KafkaConsumer, error) {
-	consumerGroup, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupName, saramaCfg)
+func NewKafkaConsumer(cfg configs.KafkaConfig, queueConfig configs.QueueConfig, saramaCfg *sarama.Config, handler QueueHandler) (QueueConsumer, error) {
+	consumerGroup, err := sarama.NewConsumerGroup(cfg.Brokers, queueConfig.GroupName, saramaCfg)
 	if err != nil {
 		return nil, err
 	}
 
+	tracker := newConsumerTracker()
+
 	return &KafkaConsumerImpl{
-		ready:           make(chan bool),
-		topics:          cfg.Topics,
+		consumerTracker: tracker,
+		topics:          queueConfig.Topics,
 		consumerGroup:   consumerGroup,
-		consumerHandler: NewKafkaConsumerHandler(handler),
+		consumerHandler: NewKafkaConsumerHandler(handler, tracker, consumerGroup),
 	}, nil
 }
 
-func (c *KafkaConsumerImpl) ResetReady() {
-	c.ready = make(chan bool)
+func (c *KafkaConsumerImpl) Pause(partitions map[string][]int32) {
+	c.setPaused(partitions, true)
+	c.consumerGroup.Pause(partitions)
 }
 
-func (c *KafkaConsumerImpl) WaitReady() {
-	<-c.ready
+func (c *KafkaConsumerImpl) Resume(partitions map[string][]int32) {
+	c.setPaused(partitions, false)
+	c.consumerGroup.Resume(partitions)
 }
 
-func (c *KafkaConsumerImpl) Consume(ctx context.Context) {
-	var wg sync.WaitGroup
-	wg.Add(1)
+func (c *KafkaConsumerImpl) Close() error {
+	return c.consumerGroup.Close()
+}
 
-	go func() {
-		defer wg.Done()
+func (c *KafkaConsumerImpl) Consume(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			slog.InfoContext(ctx, "Context cancelled, stopping consumer")
+			return
+		}
 
-		for {
-			if ctx.Err() != nil {
-				slog.InfoContext(ctx, "Context cancelled, stopping consumer")
-				return
-			}
+		if err := c.consumerGroup.Consume(ctx, c.topics, c.consumerHandler); err != nil {
+			slog.ErrorContext(ctx, "Error while consuming", slog.Any("error", err))
+		}
+	}
+}
 
-			if err := c.consumerGroup.Consume(ctx, c.topics, c.consumerHandler); err != nil {
-				slog.ErrorContext(ctx, "Error while consuming", slog.Any("error", err))
-			}
+func fromConsumerMessage(message *sarama.ConsumerMessage) *Message {
+	result := &Message{
+		Topic:     message.Topic,
+		Partition: message.Partition,
+		Offset:    message.Offset,
+		Key:       message.Key,
+		Value:     message.Value,
+		Timestamp: message.Timestamp,
+	}
 
-			c.ResetReady()
+	for _, header := range message.Headers {
+		if header != nil {
+			result.Headers = append(result.Headers, MessageHeader{Key: string(header.Key), Value: header.Value})
 		}
-	}()
+	}
 
-	c.WaitReady()
+	return result
 }
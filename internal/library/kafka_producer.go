@@ -2,29 +2,124 @@ package library
 
 import (
 	"context"
+	"fmt"
 	"github.com/IBM/sarama"
 	"salesforce-sse-worker/configs"
 )
 
 type (
-	KafkaProducer interface {
-		Produce(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
-	}
-
 	KafkaProducerImpl struct {
 		syncProducer sarama.SyncProducer
 	}
+
+	KafkaAsyncProducerImpl struct {
+		asyncProducer sarama.AsyncProducer
+	}
+
+	deliveryResult struct {
+		partition int32
+		offset    int64
+		err       error
+	}
 )
 
-func NewKafkaProducer(cfg configs.KafkaConfig, saramaCfg *sarama.Config) (KafkaProducer, error) {
-	syncProducer, err := sarama.NewSyncProducer(cfg.Brokers, saramaCfg)
+func NewKafkaProducer(cfg configs.KafkaConfig, saramaCfg *sarama.Config) (QueueProducer, error) {
+	switch cfg.ProducerMode {
+	case "", "sync":
+		syncProducer, err := sarama.NewSyncProducer(cfg.Brokers, saramaCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &KafkaProducerImpl{syncProducer: syncProducer}, nil
+	case "async":
+		return NewKafkaAsyncProducer(cfg, saramaCfg)
+	default:
+		return nil, fmt.Errorf("unsupported producer mode %q", cfg.ProducerMode)
+	}
+}
+
+func (p *KafkaProducerImpl) Produce(ctx context.Context, msg *Message) (partition int32, offset int64, err error) {
+	return p.syncProducer.SendMessage(toProducerMessage(msg))
+}
+
+func (p *KafkaProducerImpl) Close() error {
+	return p.syncProducer.Close()
+}
+
+func NewKafkaAsyncProducer(cfg configs.KafkaConfig, saramaCfg *sarama.Config) (QueueProducer, error) {
+	producerCfg := *saramaCfg
+	producerCfg.Producer.Return.Successes = true
+	producerCfg.Producer.Return.Errors = true
+
+	asyncProducer, err := sarama.NewAsyncProducer(cfg.Brokers, &producerCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &KafkaProducerImpl{syncProducer: syncProducer}, nil
+	p := &KafkaAsyncProducerImpl{asyncProducer: asyncProducer}
+	go p.dispatchSuccesses()
+	go p.dispatchErrors()
+
+	return p, nil
+}
+
+func (p *KafkaAsyncProducerImpl) Produce(ctx context.Context, msg *Message) (partition int32, offset int64, err error) {
+	result := make(chan deliveryResult, 1)
+	producerMessage := toProducerMessage(msg)
+	producerMessage.Metadata = result
+
+	select {
+	case p.asyncProducer.Input() <- producerMessage:
+	case <-ctx.Done():
+		return -1, -1, ctx.Err()
+	}
+
+	select {
+	case delivery := <-result:
+		return delivery.partition, delivery.offset, delivery.err
+	case <-ctx.Done():
+		return -1, -1, ctx.Err()
+	}
+}
+
+func (p *KafkaAsyncProducerImpl) Close() error {
+	return p.asyncProducer.Close()
+}
+
+func (p *KafkaAsyncProducerImpl) dispatchSuccesses() {
+	for msg := range p.asyncProducer.Successes() {
+		if result, ok := msg.Metadata.(chan deliveryResult); ok {
+			result <- deliveryResult{partition: msg.Partition, offset: msg.Offset}
+		}
+	}
+}
+
+func (p *KafkaAsyncProducerImpl) dispatchErrors() {
+	for producerErr := range p.asyncProducer.Errors() {
+		if result, ok := producerErr.Msg.Metadata.(chan deliveryResult); ok {
+			result <- deliveryResult{partition: -1, offset: -1, err: producerErr.Err}
+		}
+	}
 }
 
-func (p *KafkaProducerImpl) Produce(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
-	return p.syncProducer.SendMessage(msg)
+func toProducerMessage(msg *Message) *sarama.ProducerMessage {
+	producerMessage := &sarama.ProducerMessage{
+		Topic:     msg.Topic,
+		Partition: -1,
+		Value:     sarama.ByteEncoder(msg.Value),
+		Timestamp: msg.Timestamp,
+	}
+	if msg.ManualPartition {
+		producerMessage.Partition = msg.Partition
+	}
+	if msg.Key != nil {
+		producerMessage.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	for _, header := range msg.Headers {
+		producerMessage.Headers = append(producerMessage.Headers, sarama.RecordHeader{Key: []byte(header.Key), Value: header.Value})
+	}
+
+	return producerMessage
 }
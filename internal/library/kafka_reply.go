@@ -0,0 +1,101 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"salesforce-sse-worker/configs"
+	"sync"
+)
+
+type (
+	KafkaReplyListenerImpl struct {
+		*replyRegistry
+		topic              string
+		consumer           sarama.Consumer
+		partitionConsumers []sarama.PartitionConsumer
+	}
+)
+
+func NewKafkaReplyListener(cfg configs.KafkaConfig, queueConfig configs.QueueConfig, saramaCfg *sarama.Config) (QueueReplyListener, error) {
+	listener := &KafkaReplyListenerImpl{
+		replyRegistry: newReplyRegistry(),
+		topic:         queueConfig.ReplyTopic,
+	}
+
+	if queueConfig.ReplyTopic == "" {
+		return listener, nil
+	}
+
+	consumer, err := sarama.NewConsumer(cfg.Brokers, saramaCfg)
+	if err != nil {
+		return nil, err
+	}
+	listener.consumer = consumer
+
+	partitions, err := consumer.Partitions(queueConfig.ReplyTopic)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to list partitions of %s: %w", queueConfig.ReplyTopic, err), listener.Close())
+	}
+
+	for _, partition := range partitions {
+		partitionConsumer, err := consumer.ConsumePartition(queueConfig.ReplyTopic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to consume %s/%d: %w", queueConfig.ReplyTopic, partition, err), listener.Close())
+		}
+		listener.partitionConsumers = append(listener.partitionConsumers, partitionConsumer)
+	}
+
+	return listener, nil
+}
+
+func (l *KafkaReplyListenerImpl) Enabled() bool {
+	return l.consumer != nil
+}
+
+func (l *KafkaReplyListenerImpl) Topic() string {
+	return l.topic
+}
+
+func (l *KafkaReplyListenerImpl) Listen(ctx context.Context) {
+	if !l.Enabled() {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, partitionConsumer := range l.partitionConsumers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case message, ok := <-partitionConsumer.Messages():
+					if !ok {
+						return
+					}
+					l.dispatch(fromConsumerMessage(message))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (l *KafkaReplyListenerImpl) Close() error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	var errs []error
+	for _, partitionConsumer := range l.partitionConsumers {
+		errs = append(errs, partitionConsumer.Close())
+	}
+
+	return errors.Join(append(errs, l.consumer.Close())...)
+}
@@ -0,0 +1,186 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	LifecycleOrderStorage   = 0
+	LifecycleOrderMigration = 50
+	LifecycleOrderQueue     = 100
+	LifecycleOrderService   = 200
+	LifecycleOrderConsumer  = 300
+	LifecycleOrderHttp      = 400
+)
+
+type (
+	Lifecycle interface {
+		Append(hook LifecycleHook)
+		AppendBackground(name string, order int, run func(ctx context.Context) error)
+		Start(ctx context.Context) error
+		Wait(ctx context.Context) error
+		Stop(ctx context.Context) error
+	}
+
+	LifecycleHook struct {
+		Name    string
+		Order   int
+		OnStart func(ctx context.Context) error
+		OnStop  func(ctx context.Context) error
+	}
+
+	LifecycleImpl struct {
+		mu           sync.Mutex
+		startTimeout time.Duration
+		stopTimeout  time.Duration
+		hooks        []LifecycleHook
+		started      []LifecycleHook
+		failed       chan struct{}
+		failure      error
+		failOnce     sync.Once
+	}
+)
+
+func NewLifecycle(cfg configs.LifecycleConfig) Lifecycle {
+	return &LifecycleImpl{
+		startTimeout: time.Duration(cfg.StartTimeout) * time.Millisecond,
+		stopTimeout:  time.Duration(cfg.StopTimeout) * time.Millisecond,
+		failed:       make(chan struct{}),
+	}
+}
+
+func (l *LifecycleImpl) Append(hook LifecycleHook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, hook)
+}
+
+func (l *LifecycleImpl) AppendBackground(name string, order int, run func(ctx context.Context) error) {
+	var cancel context.CancelFunc
+	var runErr error
+	done := make(chan struct{})
+
+	l.Append(LifecycleHook{
+		Name:  name,
+		Order: order,
+		OnStart: func(ctx context.Context) error {
+			var runCtx context.Context
+			runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+			go func() {
+				defer close(done)
+
+				err := run(runCtx)
+				if err != nil && runCtx.Err() == nil {
+					l.fail(fmt.Errorf("%s: %w", name, err))
+					return
+				}
+				runErr = err
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return runErr
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+func (l *LifecycleImpl) Start(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := make([]LifecycleHook, len(l.hooks))
+	copy(hooks, l.hooks)
+	l.mu.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].Order < hooks[j].Order
+	})
+
+	for _, hook := range hooks {
+		if hook.OnStart != nil {
+			slog.InfoContext(ctx, "Starting component", slog.String("component", hook.Name))
+
+			if err := l.run(ctx, l.startTimeout, hook.OnStart); err != nil {
+				startErr := fmt.Errorf("failed to start %s: %w", hook.Name, err)
+				return errors.Join(startErr, l.Stop(ctx))
+			}
+		}
+
+		l.mu.Lock()
+		l.started = append(l.started, hook)
+		l.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (l *LifecycleImpl) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-l.failed:
+		return l.failure
+	}
+}
+
+func (l *LifecycleImpl) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	started := l.started
+	l.started = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		hook := started[i]
+		if hook.OnStop == nil {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Stopping component", slog.String("component", hook.Name))
+
+		if err := l.run(context.WithoutCancel(ctx), l.stopTimeout, hook.OnStop); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", hook.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *LifecycleImpl) run(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- fn(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *LifecycleImpl) fail(err error) {
+	l.failOnce.Do(func() {
+		l.failure = err
+		close(l.failed)
+	})
+}
@@ -0,0 +1,412 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"sync"
+	"time"
+)
+
+type (
+	MemoryBroker struct {
+		mu         sync.Mutex
+		partitions int
+		next       uint32
+		logs       map[string][][]*Message
+		offsets    map[string]map[string][]int64
+		updated    chan struct{}
+	}
+
+	MemoryProducerImpl struct {
+		broker *MemoryBroker
+	}
+
+	MemoryConsumerImpl struct {
+		*consumerTracker
+		topics  []string
+		group   string
+		broker  *MemoryBroker
+		handler QueueHandler
+	}
+
+	MemoryAdminImpl struct {
+		broker *MemoryBroker
+	}
+
+	MemoryReplyListenerImpl struct {
+		*replyRegistry
+		topic  string
+		group  string
+		broker *MemoryBroker
+	}
+)
+
+func NewMemoryBroker(queueConfig configs.QueueConfig) *MemoryBroker {
+	partitions := queueConfig.MemoryPartitions
+	if partitions <= 0 {
+		partitions = 1
+	}
+
+	return &MemoryBroker{
+		partitions: partitions,
+		logs:       map[string][][]*Message{},
+		offsets:    map[string]map[string][]int64{},
+		updated:    make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) Partitions() int {
+	return b.partitions
+}
+
+func (b *MemoryBroker) Produce(msg *Message) (int32, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	logs := b.topic(msg.Topic)
+	partition := msg.Partition
+	if !msg.ManualPartition {
+		partition = b.partitionFor(msg.Key)
+	}
+
+	stored := *msg
+	stored.Partition = partition
+	stored.Offset = int64(len(logs[partition]))
+	if stored.Timestamp.IsZero() {
+		stored.Timestamp = time.Now()
+	}
+	logs[partition] = append(logs[partition], &stored)
+
+	close(b.updated)
+	b.updated = make(chan struct{})
+
+	return stored.Partition, stored.Offset
+}
+
+func (b *MemoryBroker) Lag(group string, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	logs := b.topic(topic)
+	offsets := b.groupOffsets(group, topic)
+
+	var lag int64
+	for partition := range logs {
+		lag += int64(len(logs[partition])) - offsets[partition]
+	}
+
+	return lag
+}
+
+func (b *MemoryBroker) SeekNewest(group string, topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	logs := b.topic(topic)
+	offsets := b.groupOffsets(group, topic)
+	for partition := range logs {
+		offsets[partition] = int64(len(logs[partition]))
+	}
+}
+
+func (b *MemoryBroker) Committed(group string, topic string) map[int32]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	committed := map[int32]int64{}
+	for partition, offset := range b.groupOffsets(group, topic) {
+		committed[int32(partition)] = offset
+	}
+
+	return committed
+}
+
+func (b *MemoryBroker) Seek(group string, topic string, offsets map[int32]int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	groupOffsets := b.groupOffsets(group, topic)
+	for partition, offset := range offsets {
+		if partition < 0 || int(partition) >= len(groupOffsets) {
+			return fmt.Errorf("partition %d out of range", partition)
+		}
+		groupOffsets[partition] = offset
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Read(topic string, limit int) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages := []*Message{}
+	for _, log := range b.topic(topic) {
+		for _, message := range log[max(0, len(log)-limit):] {
+			copied := *message
+			messages = append(messages, &copied)
+		}
+	}
+
+	return messages
+}
+
+func (b *MemoryBroker) SeekTimestamp(group string, topic string, timestamp time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	logs := b.topic(topic)
+	offsets := b.groupOffsets(group, topic)
+	for partition, log := range logs {
+		offsets[partition] = int64(len(log))
+		for _, message := range log {
+			if !message.Timestamp.Before(timestamp) {
+				offsets[partition] = message.Offset
+				break
+			}
+		}
+	}
+}
+
+func (b *MemoryBroker) Consume(ctx context.Context, group string, topic string, handle func(ctx context.Context, message *Message) bool) {
+	var wg sync.WaitGroup
+	for partition := 0; partition < b.partitions; partition++ {
+		wg.Add(1)
+
+		go func(partition int) {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				message, updated := b.fetch(group, topic, partition)
+				if message == nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-updated:
+						continue
+					}
+				}
+
+				if handle(ctx, message) {
+					b.commit(group, topic, partition, message.Offset+1)
+				}
+			}
+		}(partition)
+	}
+
+	wg.Wait()
+}
+
+func (b *MemoryBroker) fetch(group string, topic string, partition int) (*Message, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	logs := b.topic(topic)
+	offset := b.groupOffsets(group, topic)[partition]
+	if offset >= int64(len(logs[partition])) {
+		return nil, b.updated
+	}
+
+	message := *logs[partition][offset]
+
+	return &message, b.updated
+}
+
+func (b *MemoryBroker) commit(group string, topic string, partition int, offset int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.groupOffsets(group, topic)[partition] = offset
+}
+
+func (b *MemoryBroker) topic(topic string) [][]*Message {
+	logs, ok := b.logs[topic]
+	if !ok {
+		logs = make([][]*Message, b.partitions)
+		b.logs[topic] = logs
+	}
+
+	return logs
+}
+
+func (b *MemoryBroker) groupOffsets(group string, topic string) []int64 {
+	topics, ok := b.offsets[group]
+	if !ok {
+		topics = map[string][]int64{}
+		b.offsets[group] = topics
+	}
+
+	offsets, ok := topics[topic]
+	if !ok {
+		offsets = make([]int64, b.partitions)
+		topics[topic] = offsets
+	}
+
+	return offsets
+}
+
+func (b *MemoryBroker) partitionFor(key []byte) int32 {
+	if key == nil {
+		b.next++
+		return int32(b.next % uint32(b.partitions))
+	}
+
+	return hashPartition(key, b.partitions)
+}
+
+func NewMemoryProducer(broker *MemoryBroker) QueueProducer {
+	return &MemoryProducerImpl{broker: broker}
+}
+
+func (p *MemoryProducerImpl) Produce(ctx context.Context, msg *Message) (partition int32, offset int64, err error) {
+	if err := ctx.Err(); err != nil {
+		return -1, -1, err
+	}
+
+	if msg.ManualPartition && (msg.Partition < 0 || int(msg.Partition) >= p.broker.Partitions()) {
+		return -1, -1, fmt.Errorf("partition %d out of range", msg.Partition)
+	}
+
+	partition, offset = p.broker.Produce(msg)
+
+	return partition, offset, nil
+}
+
+func (p *MemoryProducerImpl) Close() error {
+	return nil
+}
+
+func NewMemoryConsumer(cfg configs.QueueConfig, broker *MemoryBroker, handler QueueHandler) QueueConsumer {
+	return &MemoryConsumerImpl{
+		consumerTracker: newConsumerTracker(),
+		topics:          cfg.Topics,
+		group:           cfg.GroupName,
+		broker:          broker,
+		handler:         handler,
+	}
+}
+
+func (c *MemoryConsumerImpl) Pause(partitions map[string][]int32) {
+	c.setPaused(partitions, true)
+}
+
+func (c *MemoryConsumerImpl) Resume(partitions map[string][]int32) {
+	c.setPaused(partitions, false)
+}
+
+func (c *MemoryConsumerImpl) Close() error {
+	return nil
+}
+
+func (c *MemoryConsumerImpl) Consume(ctx context.Context) {
+	partitions := make([]int32, c.broker.Partitions())
+	for partition := range partitions {
+		partitions[partition] = int32(partition)
+	}
+
+	claims := map[string][]int32{}
+	for _, topic := range c.topics {
+		claims[topic] = partitions
+	}
+
+	c.nextGeneration(claims)
+	if err := c.handler.Setup(ctx, claims); err != nil {
+		slog.ErrorContext(ctx, "Failed to set up consumer", slog.Any("error", err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, topic := range c.topics {
+		wg.Add(1)
+
+		go func(topic string) {
+			defer wg.Done()
+
+			c.broker.Consume(ctx, c.group, topic, func(ctx context.Context, message *Message) bool {
+				if !c.waitResumed(ctx, message.Topic, message.Partition) {
+					return false
+				}
+
+				c.begin(message)
+				if err := c.handler.Handle(ctx, message); err != nil {
+					slog.ErrorContext(ctx, "Failed to handle message", slog.Any("error", err))
+				}
+				c.end(message)
+
+				return true
+			})
+		}(topic)
+	}
+	wg.Wait()
+
+	slog.InfoContext(ctx, "Context cancelled, stopping consumer")
+
+	if err := c.handler.Cleanup(context.WithoutCancel(ctx), claims); err != nil {
+		slog.ErrorContext(ctx, "Failed to clean up consumer", slog.Any("error", err))
+	}
+}
+
+func NewMemoryAdmin(broker *MemoryBroker) QueueAdmin {
+	return &MemoryAdminImpl{broker: broker}
+}
+
+func (a *MemoryAdminImpl) PartitionCount(ctx context.Context, topic string) (int, error) {
+	return a.broker.Partitions(), nil
+}
+
+func (a *MemoryAdminImpl) ConsumerGroupLag(ctx context.Context, group string, topic string) (int64, error) {
+	return a.broker.Lag(group, topic), nil
+}
+
+func (a *MemoryAdminImpl) ReadMessages(ctx context.Context, topic string, limit int) ([]*Message, error) {
+	return a.broker.Read(topic, limit), nil
+}
+
+func (a *MemoryAdminImpl) ResetOffsets(ctx context.Context, group string, topic string, timestamp time.Time) error {
+	a.broker.SeekTimestamp(group, topic, timestamp)
+
+	return nil
+}
+
+func (a *MemoryAdminImpl) CommittedOffsets(ctx context.Context, group string, topic string) (map[int32]int64, error) {
+	return a.broker.Committed(group, topic), nil
+}
+
+func (a *MemoryAdminImpl) CommitOffsets(ctx context.Context, group string, topic string, offsets map[int32]int64) error {
+	return a.broker.Seek(group, topic, offsets)
+}
+
+func NewMemoryReplyListener(cfg configs.QueueConfig, broker *MemoryBroker) QueueReplyListener {
+	return &MemoryReplyListenerImpl{
+		replyRegistry: newReplyRegistry(),
+		topic:         cfg.ReplyTopic,
+		group:         cfg.GroupName + "-reply",
+		broker:        broker,
+	}
+}
+
+func (l *MemoryReplyListenerImpl) Enabled() bool {
+	return l.topic != ""
+}
+
+func (l *MemoryReplyListenerImpl) Topic() string {
+	return l.topic
+}
+
+func (l *MemoryReplyListenerImpl) Listen(ctx context.Context) {
+	if !l.Enabled() {
+		return
+	}
+
+	l.broker.SeekNewest(l.group, l.topic)
+	l.broker.Consume(ctx, l.group, l.topic, func(ctx context.Context, message *Message) bool {
+		l.dispatch(message)
+		return true
+	})
+}
+
+func (l *MemoryReplyListenerImpl) Close() error {
+	return nil
+}
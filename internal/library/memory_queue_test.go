@@ -0,0 +1,237 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"salesforce-sse-worker/configs"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingQueueHandler struct {
+	mu       sync.Mutex
+	claims   map[string][]int32
+	setups   []map[string][]int32
+	cleanups []map[string][]int32
+	messages []*Message
+	handled  chan *Message
+}
+
+func newRecordingQueueHandler() *recordingQueueHandler {
+	return &recordingQueueHandler{handled: make(chan *Message, 100)}
+}
+
+func (h *recordingQueueHandler) Setup(ctx context.Context, claims map[string][]int32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.claims = claims
+	h.setups = append(h.setups, claims)
+
+	return nil
+}
+
+func (h *recordingQueueHandler) Cleanup(ctx context.Context, claims map[string][]int32) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cleanups = append(h.cleanups, claims)
+
+	return nil
+}
+
+func (h *recordingQueueHandler) Handle(ctx context.Context, message *Message) error {
+	h.mu.Lock()
+	h.messages = append(h.messages, message)
+	h.mu.Unlock()
+
+	h.handled <- message
+
+	return nil
+}
+
+func (h *recordingQueueHandler) wait(t *testing.T, count int) []*Message {
+	t.Helper()
+
+	var messages []*Message
+	for len(messages) < count {
+		select {
+		case message := <-h.handled:
+			messages = append(messages, message)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d messages, want %d", len(messages), count)
+		}
+	}
+
+	return messages
+}
+
+func startMemoryConsumer(t *testing.T, broker *MemoryBroker, handler QueueHandler) QueueConsumer {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer := NewMemoryConsumer(configs.QueueConfig{Topics: []string{"commands"}, GroupName: "worker"}, broker, handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Consume(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return consumer
+}
+
+func TestMemoryQueueDeliversMessagesInPartitionOrder(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(configs.QueueConfig{MemoryPartitions: 4})
+	producer := NewMemoryProducer(broker)
+
+	keys := []string{"conversation-a", "conversation-b", "conversation-c"}
+	partitions := map[string]int32{}
+	for i := 0; i < 30; i++ {
+		key := keys[i%len(keys)]
+
+		partition, _, err := producer.Produce(ctx, &Message{Topic: "commands", Key: []byte(key), Value: []byte(fmt.Sprint(i))})
+		if err != nil {
+			t.Fatalf("produce: %v", err)
+		}
+
+		if previous, ok := partitions[key]; ok && previous != partition {
+			t.Fatalf("key %s moved from partition %d to %d", key, previous, partition)
+		}
+		partitions[key] = partition
+	}
+
+	handler := newRecordingQueueHandler()
+	consumer := startMemoryConsumer(t, broker, handler)
+
+	last := map[string]int{}
+	for _, message := range handler.wait(t, 30) {
+		var value int
+		if _, err := fmt.Sscan(string(message.Value), &value); err != nil {
+			t.Fatalf("decode value: %v", err)
+		}
+
+		key := string(message.Key)
+		if previous, ok := last[key]; ok && value < previous {
+			t.Fatalf("key %s: got %d after %d", key, value, previous)
+		}
+		last[key] = value
+	}
+
+	handler.mu.Lock()
+	claimed := len(handler.claims["commands"])
+	handler.mu.Unlock()
+	if claimed != 4 {
+		t.Fatalf("got %d claimed partitions, want 4", claimed)
+	}
+
+	admin := NewMemoryAdmin(broker)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lag, err := admin.ConsumerGroupLag(ctx, "worker", "commands")
+		if err != nil {
+			t.Fatalf("lag: %v", err)
+		}
+		if lag == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got lag %d, want 0", lag)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status := consumer.Status(); len(status.Partitions) != 4 || status.Generation != 1 {
+		t.Fatalf("got status %+v", status)
+	}
+}
+
+func TestMemoryQueuePausesPartitions(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(configs.QueueConfig{MemoryPartitions: 1})
+	producer := NewMemoryProducer(broker)
+
+	handler := newRecordingQueueHandler()
+	consumer := startMemoryConsumer(t, broker, handler)
+	consumer.Pause(map[string][]int32{"commands": {0}})
+
+	if _, _, err := producer.Produce(ctx, &Message{Topic: "commands", Key: []byte("key"), Value: []byte("paused")}); err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+
+	select {
+	case message := <-handler.handled:
+		t.Fatalf("handled %s while paused", message.Value)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	consumer.Resume(map[string][]int32{"commands": {0}})
+	if messages := handler.wait(t, 1); string(messages[0].Value) != "paused" {
+		t.Fatalf("got %s", messages[0].Value)
+	}
+}
+
+func TestMemoryReplyListenerDispatchesByCorrelationId(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broker := NewMemoryBroker(configs.QueueConfig{MemoryPartitions: 2})
+	listener := NewMemoryReplyListener(configs.QueueConfig{GroupName: "server", ReplyTopic: "replies"}, broker)
+	replies := listener.Register("correlation")
+	defer listener.Unregister("correlation")
+
+	go listener.Listen(ctx)
+
+	producer := NewMemoryProducer(broker)
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, _, err := producer.Produce(ctx, &Message{
+			Topic:   "replies",
+			Key:     []byte("correlation"),
+			Value:   []byte("reply"),
+			Headers: []MessageHeader{{Key: HeaderCorrelationId, Value: []byte("correlation")}},
+		}); err != nil {
+			t.Fatalf("produce: %v", err)
+		}
+
+		select {
+		case reply := <-replies:
+			if string(reply.Value) != "reply" {
+				t.Fatalf("got reply %s", reply.Value)
+			}
+			return
+		case <-deadline:
+			t.Fatal("reply was not dispatched")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestMemoryAdminCommitsOffsets(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(configs.QueueConfig{MemoryPartitions: 2})
+	admin := NewMemoryAdmin(broker)
+
+	if err := admin.CommitOffsets(ctx, "replay", "dlq", map[int32]int64{1: 3}); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	committed, err := admin.CommittedOffsets(ctx, "replay", "dlq")
+	if err != nil {
+		t.Fatalf("committed: %v", err)
+	}
+	if committed[0] != 0 || committed[1] != 3 {
+		t.Fatalf("got committed offsets %v", committed)
+	}
+
+	if err := admin.CommitOffsets(ctx, "replay", "dlq", map[int32]int64{2: 1}); err == nil {
+		t.Fatal("committed an offset for a partition out of range")
+	}
+}
@@ -9,28 +9,127 @@ import (
 
 type (
 	MongoDatabase interface {
-		Find(ctx context.Context, collection string, findQuery map[string]interface{}) (*mongo.Cursor, error)
-		FindOne(ctx context.Context, collection string, findQuery map[string]interface{}) *mongo.SingleResult
-		ReplaceOne(ctx context.Context, collection string, query interface{}, data interface{}) (result *mongo.UpdateResult, err error)
+		Find(ctx context.Context, collection string, findQuery map[string]interface{}, opts ...options.Lister[options.FindOptions]) (*mongo.Cursor, error)
+		FindOne(ctx context.Context, collection string, findQuery map[string]interface{}, opts ...options.Lister[options.FindOneOptions]) *mongo.SingleResult
+		FindOneAndUpdate(ctx context.Context, collection string, query map[string]interface{}, update interface{}, opts ...options.Lister[options.FindOneAndUpdateOptions]) *mongo.SingleResult
+		CountDocuments(ctx context.Context, collection string, query map[string]interface{}) (int64, error)
+		Aggregate(ctx context.Context, collection string, pipeline interface{}) (*mongo.Cursor, error)
+		InsertOne(ctx context.Context, collection string, data interface{}) (*mongo.InsertOneResult, error)
+		InsertMany(ctx context.Context, collection string, data []interface{}) (*mongo.InsertManyResult, error)
+		ReplaceOne(ctx context.Context, collection string, query interface{}, data interface{}, opts ...options.Lister[options.ReplaceOptions]) (result *mongo.UpdateResult, err error)
+		UpdateOne(ctx context.Context, collection string, query map[string]interface{}, update interface{}, opts ...options.Lister[options.UpdateOneOptions]) (*mongo.UpdateResult, error)
+		UpdateMany(ctx context.Context, collection string, query map[string]interface{}, update interface{}) (*mongo.UpdateResult, error)
+		DeleteOne(ctx context.Context, collection string, query map[string]interface{}) (*mongo.DeleteResult, error)
+		DeleteMany(ctx context.Context, collection string, query map[string]interface{}) (*mongo.DeleteResult, error)
+		BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel) (*mongo.BulkWriteResult, error)
+		Watch(ctx context.Context, collection string, pipeline interface{}) (MongoChangeStream, error)
+		CreateIndex(ctx context.Context, collection string, index mongo.IndexModel) (string, error)
+		WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	}
+
+	MongoChangeStream interface {
+		Next(ctx context.Context) bool
+		Decode(val interface{}) error
+		Err() error
+		Close(ctx context.Context) error
 	}
 
 	MongoDatabaseImpl struct {
-		db *mongo.Database
+		client *mongo.Client
+		db     *mongo.Database
 	}
 )
 
-func NewMongoDatabase(cfg configs.MongoConfig, client *mongo.Client) MongoDatabase {
-	return &MongoDatabaseImpl{db: client.Database(cfg.DatabaseName)}
+func NewMongoDatabase(cfg configs.MongoConfig, client *mongo.Client, lifecycle Lifecycle) MongoDatabase {
+	lifecycle.Append(LifecycleHook{
+		Name:  "mongo",
+		Order: LifecycleOrderStorage,
+		OnStart: func(ctx context.Context) error {
+			return client.Ping(ctx, nil)
+		},
+		OnStop: func(ctx context.Context) error {
+			return client.Disconnect(ctx)
+		},
+	})
+
+	return &MongoDatabaseImpl{client: client, db: client.Database(cfg.DatabaseName)}
+}
+
+func (m *MongoDatabaseImpl) Find(ctx context.Context, collection string, query map[string]interface{}, opts ...options.Lister[options.FindOptions]) (*mongo.Cursor, error) {
+	return m.db.Collection(collection).Find(ctx, query, opts...)
+}
+
+func (m *MongoDatabaseImpl) FindOne(ctx context.Context, collection string, query map[string]interface{}, opts ...options.Lister[options.FindOneOptions]) *mongo.SingleResult {
+	return m.db.Collection(collection).FindOne(ctx, query, opts...)
+}
+
+func (m *MongoDatabaseImpl) FindOneAndUpdate(ctx context.Context, collection string, query map[string]interface{}, update interface{}, opts ...options.Lister[options.FindOneAndUpdateOptions]) *mongo.SingleResult {
+	return m.db.Collection(collection).FindOneAndUpdate(ctx, query, update, opts...)
+}
+
+func (m *MongoDatabaseImpl) CountDocuments(ctx context.Context, collection string, query map[string]interface{}) (int64, error) {
+	return m.db.Collection(collection).CountDocuments(ctx, query)
+}
+
+func (m *MongoDatabaseImpl) Aggregate(ctx context.Context, collection string, pipeline interface{}) (*mongo.Cursor, error) {
+	return m.db.Collection(collection).Aggregate(ctx, pipeline)
+}
+
+func (m *MongoDatabaseImpl) InsertOne(ctx context.Context, collection string, data interface{}) (*mongo.InsertOneResult, error) {
+	return m.db.Collection(collection).InsertOne(ctx, data)
+}
+
+func (m *MongoDatabaseImpl) InsertMany(ctx context.Context, collection string, data []interface{}) (*mongo.InsertManyResult, error) {
+	return m.db.Collection(collection).InsertMany(ctx, data)
 }
 
-func (m *MongoDatabaseImpl) Find(ctx context.Context, collection string, query map[string]interface{}) (*mongo.Cursor, error) {
-	return m.db.Collection(collection).Find(ctx, query)
+func (m *MongoDatabaseImpl) ReplaceOne(ctx context.Context, collection string, query interface{}, data interface{}, opts ...options.Lister[options.ReplaceOptions]) (result *mongo.UpdateResult, err error) {
+	return m.db.Collection(collection).ReplaceOne(ctx, query, data, opts...)
 }
 
-func (m *MongoDatabaseImpl) FindOne(ctx context.Context, collection string, query map[string]interface{}) *mongo.SingleResult {
-	return m.db.Collection(collection).FindOne(ctx, query)
+func (m *MongoDatabaseImpl) UpdateOne(ctx context.Context, collection string, query map[string]interface{}, update interface{}, opts ...options.Lister[options.UpdateOneOptions]) (*mongo.UpdateResult, error) {
+	return m.db.Collection(collection).UpdateOne(ctx, query, update, opts...)
 }
 
-func (m *MongoDatabaseImpl) ReplaceOne(ctx context.Context, collection string, query interface{}, data interface{}) (result *mongo.UpdateResult, err error) {
-	return m.db.Collection(collection).ReplaceOne(ctx, query, data, options.Replace().SetUpsert(true))
+func (m *MongoDatabaseImpl) UpdateMany(ctx context.Context, collection string, query map[string]interface{}, update interface{}) (*mongo.UpdateResult, error) {
+	return m.db.Collection(collection).UpdateMany(ctx, query, update)
+}
+
+func (m *MongoDatabaseImpl) DeleteOne(ctx context.Context, collection string, query map[string]interface{}) (*mongo.DeleteResult, error) {
+	return m.db.Collection(collection).DeleteOne(ctx, query)
+}
+
+func (m *MongoDatabaseImpl) DeleteMany(ctx context.Context, collection string, query map[string]interface{}) (*mongo.DeleteResult, error) {
+	return m.db.Collection(collection).DeleteMany(ctx, query)
+}
+
+func (m *MongoDatabaseImpl) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	return m.db.Collection(collection).BulkWrite(ctx, models)
+}
+
+func (m *MongoDatabaseImpl) Watch(ctx context.Context, collection string, pipeline interface{}) (MongoChangeStream, error) {
+	changeStream, err := m.db.Collection(collection).Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return nil, err
+	}
+
+	return changeStream, nil
+}
+
+func (m *MongoDatabaseImpl) CreateIndex(ctx context.Context, collection string, index mongo.IndexModel) (string, error) {
+	return m.db.Collection(collection).Indexes().CreateOne(ctx, index)
+}
+
+func (m *MongoDatabaseImpl) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+
+	return err
 }
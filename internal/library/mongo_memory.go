@@ -0,0 +1,826 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"strings"
+	"sync"
+)
+
+var ErrMemoryMongoUnsupported = errors.New("not supported by the memory mongo database")
+
+type (
+	MemoryMongoDatabaseImpl struct {
+		mu          sync.Mutex
+		collections map[string]*memoryCollection
+		streams     map[*memoryChangeStream]bool
+		pending     []memoryChangeEvent
+		sequence    int64
+	}
+
+	memoryCollection struct {
+		documents []bson.D
+		indexes   []memoryIndex
+	}
+
+	memoryIndex struct {
+		name   string
+		keys   []string
+		unique bool
+	}
+
+	memoryChangeEvent struct {
+		collection string
+		event      bson.D
+	}
+
+	memoryChangeStream struct {
+		database   *MemoryMongoDatabaseImpl
+		collection string
+		filters    []bson.D
+		mu         sync.Mutex
+		queue      []bson.D
+		notify     chan struct{}
+		closed     bool
+		current    bson.D
+		err        error
+	}
+
+	memoryTransactionKey struct{}
+)
+
+func NewMemoryMongoDatabase() MongoDatabase {
+	return &MemoryMongoDatabaseImpl{
+		collections: map[string]*memoryCollection{},
+		streams:     map[*memoryChangeStream]bool{},
+	}
+}
+
+func (m *MemoryMongoDatabaseImpl) Find(ctx context.Context, collection string, query map[string]interface{}, opts ...options.Lister[options.FindOptions]) (*mongo.Cursor, error) {
+	args, err := listOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defer m.lock(ctx)()
+
+	documents, err := m.find(collection, query, args.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	documents = skipLimit(documents, args.Skip, args.Limit)
+	if documents, err = projectDocuments(documents, args.Projection); err != nil {
+		return nil, err
+	}
+
+	return newMemoryCursor(documents)
+}
+
+func (m *MemoryMongoDatabaseImpl) FindOne(ctx context.Context, collection string, query map[string]interface{}, opts ...options.Lister[options.FindOneOptions]) *mongo.SingleResult {
+	args, err := listOptions(opts)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+
+	defer m.lock(ctx)()
+
+	documents, err := m.find(collection, query, args.Sort)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+
+	documents = skipLimit(documents, args.Skip, nil)
+	if len(documents) == 0 {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+
+	document, err := projectDocument(documents[0], args.Projection)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+
+	return mongo.NewSingleResultFromDocument(document, nil, nil)
+}
+
+func (m *MemoryMongoDatabaseImpl) FindOneAndUpdate(ctx context.Context, collection string, query map[string]interface{}, update interface{}, opts ...options.Lister[options.FindOneAndUpdateOptions]) *mongo.SingleResult {
+	args, err := listOptions(opts)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+
+	defer m.lock(ctx)()
+
+	before, after, err := m.updateOne(ctx, collection, query, update, args.Sort, args.Upsert != nil && *args.Upsert)
+	if err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+
+	document := before
+	if args.ReturnDocument != nil && *args.ReturnDocument == options.After {
+		document = after
+	}
+	if document == nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+
+	if document, err = projectDocument(document, args.Projection); err != nil {
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	}
+
+	return mongo.NewSingleResultFromDocument(document, nil, nil)
+}
+
+func (m *MemoryMongoDatabaseImpl) CountDocuments(ctx context.Context, collection string, query map[string]interface{}) (int64, error) {
+	defer m.lock(ctx)()
+
+	documents, err := m.find(collection, query, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(documents)), nil
+}
+
+func (m *MemoryMongoDatabaseImpl) Aggregate(ctx context.Context, collection string, pipeline interface{}) (*mongo.Cursor, error) {
+	stages, err := toDocuments(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := m.lock(ctx)
+	documents, err := m.find(collection, nil, nil)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if documents, err = aggregateDocuments(documents, stages); err != nil {
+		return nil, err
+	}
+
+	return newMemoryCursor(documents)
+}
+
+func (m *MemoryMongoDatabaseImpl) InsertOne(ctx context.Context, collection string, data interface{}) (*mongo.InsertOneResult, error) {
+	document, err := toDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	defer m.lock(ctx)()
+
+	document = withId(document, nil)
+	if err := m.write(ctx, collection, "", func(documents []bson.D) ([]bson.D, error) {
+		return append(documents, document), nil
+	}); err != nil {
+		return nil, err
+	}
+
+	id, _ := lookupPath(document, "_id")
+
+	return &mongo.InsertOneResult{InsertedID: id, Acknowledged: true}, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) InsertMany(ctx context.Context, collection string, data []interface{}) (*mongo.InsertManyResult, error) {
+	inserted := make([]bson.D, 0, len(data))
+	for _, item := range data {
+		document, err := toDocument(item)
+		if err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, withId(document, nil))
+	}
+
+	defer m.lock(ctx)()
+
+	result := &mongo.InsertManyResult{InsertedIDs: []interface{}{}, Acknowledged: true}
+	for i, document := range inserted {
+		if err := m.write(ctx, collection, "", func(documents []bson.D) ([]bson.D, error) {
+			return append(documents, document), nil
+		}); err != nil {
+			return result, fmt.Errorf("failed to insert document %d: %w", i, err)
+		}
+
+		id, _ := lookupPath(document, "_id")
+		result.InsertedIDs = append(result.InsertedIDs, id)
+	}
+
+	return result, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) ReplaceOne(ctx context.Context, collection string, query interface{}, data interface{}, opts ...options.Lister[options.ReplaceOptions]) (*mongo.UpdateResult, error) {
+	args, err := listOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := toDocument(query)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := toReplacement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	defer m.lock(ctx)()
+
+	return m.replaceOne(ctx, collection, filter, replacement, args.Sort, args.Upsert != nil && *args.Upsert)
+}
+
+func (m *MemoryMongoDatabaseImpl) UpdateOne(ctx context.Context, collection string, query map[string]interface{}, update interface{}, opts ...options.Lister[options.UpdateOneOptions]) (*mongo.UpdateResult, error) {
+	args, err := listOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defer m.lock(ctx)()
+
+	before, after, err := m.updateOne(ctx, collection, query, update, args.Sort, args.Upsert != nil && *args.Upsert)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateResult(before, after), nil
+}
+
+func (m *MemoryMongoDatabaseImpl) UpdateMany(ctx context.Context, collection string, query map[string]interface{}, update interface{}) (*mongo.UpdateResult, error) {
+	filter, err := toDocument(query)
+	if err != nil {
+		return nil, err
+	}
+
+	operators, err := toUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	defer m.lock(ctx)()
+
+	return m.updateMany(ctx, collection, filter, operators)
+}
+
+func (m *MemoryMongoDatabaseImpl) DeleteOne(ctx context.Context, collection string, query map[string]interface{}) (*mongo.DeleteResult, error) {
+	defer m.lock(ctx)()
+
+	return m.delete(ctx, collection, query, 1)
+}
+
+func (m *MemoryMongoDatabaseImpl) DeleteMany(ctx context.Context, collection string, query map[string]interface{}) (*mongo.DeleteResult, error) {
+	defer m.lock(ctx)()
+
+	return m.delete(ctx, collection, query, -1)
+}
+
+func (m *MemoryMongoDatabaseImpl) BulkWrite(ctx context.Context, collection string, models []mongo.WriteModel) (*mongo.BulkWriteResult, error) {
+	defer m.lock(ctx)()
+
+	result := &mongo.BulkWriteResult{UpsertedIDs: map[int64]interface{}{}, Acknowledged: true}
+	for i, model := range models {
+		if err := m.bulkWrite(ctx, collection, model, int64(i), result); err != nil {
+			return result, fmt.Errorf("failed to apply write model %d: %w", i, err)
+		}
+	}
+
+	return result, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) Watch(ctx context.Context, collection string, pipeline interface{}) (MongoChangeStream, error) {
+	stages, err := toDocuments(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &memoryChangeStream{database: m, collection: collection, notify: make(chan struct{}, 1)}
+	for _, stage := range stages {
+		if len(stage) != 1 || stage[0].Key != "$match" {
+			return nil, fmt.Errorf("change stream stage %v: %w", stage, ErrMemoryMongoUnsupported)
+		}
+
+		filter, err := toDocument(stage[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		stream.filters = append(stream.filters, filter)
+	}
+
+	defer m.lock(ctx)()
+	m.streams[stream] = true
+
+	return stream, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) CreateIndex(ctx context.Context, collection string, index mongo.IndexModel) (string, error) {
+	keys, err := toDocument(index.Keys)
+	if err != nil {
+		return "", err
+	}
+
+	created := memoryIndex{}
+	for _, key := range keys {
+		created.keys = append(created.keys, key.Key)
+	}
+
+	if index.Options != nil {
+		args, err := listOptions[options.IndexOptions]([]options.Lister[options.IndexOptions]{index.Options})
+		if err != nil {
+			return "", err
+		}
+		if args.Name != nil {
+			created.name = *args.Name
+		}
+		created.unique = args.Unique != nil && *args.Unique
+	}
+	if created.name == "" {
+		created.name = strings.Join(created.keys, "_")
+	}
+
+	defer m.lock(ctx)()
+
+	current := m.collection(collection)
+	for _, existing := range current.indexes {
+		if existing.name == created.name {
+			return created.name, nil
+		}
+	}
+
+	if err := checkUnique(collection, current.documents, append(current.indexes, created)); err != nil {
+		return "", err
+	}
+	current.indexes = append(current.indexes, created)
+
+	return created.name, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTransactionKey{}) == m {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := map[string]*memoryCollection{}
+	for name, current := range m.collections {
+		snapshot[name] = &memoryCollection{
+			documents: append([]bson.D{}, current.documents...),
+			indexes:   append([]memoryIndex{}, current.indexes...),
+		}
+	}
+
+	m.pending = nil
+	if err := fn(context.WithValue(ctx, memoryTransactionKey{}, m)); err != nil {
+		m.collections, m.pending = snapshot, nil
+		return err
+	}
+
+	for _, change := range m.pending {
+		m.deliver(change)
+	}
+	m.pending = nil
+
+	return nil
+}
+
+func (m *MemoryMongoDatabaseImpl) lock(ctx context.Context) func() {
+	if ctx.Value(memoryTransactionKey{}) == m {
+		return func() {}
+	}
+
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+func (m *MemoryMongoDatabaseImpl) collection(name string) *memoryCollection {
+	current, ok := m.collections[name]
+	if !ok {
+		current = &memoryCollection{}
+		m.collections[name] = current
+	}
+
+	return current
+}
+
+func (m *MemoryMongoDatabaseImpl) find(collection string, query interface{}, sortBy interface{}) ([]bson.D, error) {
+	filter, err := toDocument(query)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := []bson.D{}
+	for _, document := range m.collection(collection).documents {
+		ok, err := matchDocument(document, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			documents = append(documents, document)
+		}
+	}
+
+	if sortBy != nil {
+		if err := sortDocuments(documents, sortBy); err != nil {
+			return nil, err
+		}
+	}
+
+	return documents, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) updateOne(ctx context.Context, collection string, query interface{}, update interface{}, sortBy interface{}, upsert bool) (bson.D, bson.D, error) {
+	filter, err := toDocument(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	operators, err := toUpdate(update)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var before, after bson.D
+	err = m.write(ctx, collection, "update", func(documents []bson.D) ([]bson.D, error) {
+		index, err := firstMatch(documents, filter, sortBy)
+		if err != nil {
+			return nil, err
+		}
+
+		if index >= 0 {
+			before = documents[index]
+			if after, err = applyUpdate(before, operators, false); err != nil {
+				return nil, err
+			}
+
+			documents[index] = after
+			return documents, nil
+		}
+
+		if !upsert {
+			return documents, nil
+		}
+
+		seed := bson.D{}
+		for _, element := range filter {
+			if !strings.HasPrefix(element.Key, "$") && !isOperatorDocument(element.Value) {
+				seed = setPath(seed, element.Key, element.Value)
+			}
+		}
+
+		if after, err = applyUpdate(seed, operators, true); err != nil {
+			return nil, err
+		}
+		after = withId(after, nil)
+
+		return append(documents, after), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) replaceOne(ctx context.Context, collection string, filter bson.D, replacement bson.D, sortBy interface{}, upsert bool) (*mongo.UpdateResult, error) {
+	result := &mongo.UpdateResult{Acknowledged: true}
+	err := m.write(ctx, collection, "replace", func(documents []bson.D) ([]bson.D, error) {
+		index, err := firstMatch(documents, filter, sortBy)
+		if err != nil {
+			return nil, err
+		}
+
+		if index >= 0 {
+			id, _ := lookupPath(documents[index], "_id")
+			if replacementId, ok := lookupPath(replacement, "_id"); ok && !equalsValue(id, true, replacementId) {
+				return nil, fmt.Errorf("the _id field cannot be modified")
+			}
+			document := withId(replacement, id)
+
+			result.MatchedCount = 1
+			if !documentsEqual(documents[index], document) {
+				result.ModifiedCount = 1
+			}
+
+			documents[index] = document
+			return documents, nil
+		}
+
+		if !upsert {
+			return documents, nil
+		}
+
+		id, _ := lookupPath(replacement, "_id")
+		if id == nil {
+			id, _ = equalityValue(filter, "_id")
+		}
+		document := withId(replacement, id)
+		result.UpsertedCount = 1
+		result.UpsertedID, _ = lookupPath(document, "_id")
+
+		return append(documents, document), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) updateMany(ctx context.Context, collection string, filter bson.D, operators bson.D) (*mongo.UpdateResult, error) {
+	result := &mongo.UpdateResult{Acknowledged: true}
+	err := m.write(ctx, collection, "update", func(documents []bson.D) ([]bson.D, error) {
+		for i, document := range documents {
+			ok, err := matchDocument(document, filter)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			updated, err := applyUpdate(document, operators, false)
+			if err != nil {
+				return nil, err
+			}
+
+			result.MatchedCount++
+			if !documentsEqual(document, updated) {
+				result.ModifiedCount++
+			}
+			documents[i] = updated
+		}
+
+		return documents, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) delete(ctx context.Context, collection string, query interface{}, limit int) (*mongo.DeleteResult, error) {
+	filter, err := toDocument(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &mongo.DeleteResult{Acknowledged: true}
+	err = m.write(ctx, collection, "", func(documents []bson.D) ([]bson.D, error) {
+		kept := []bson.D{}
+		for _, document := range documents {
+			if limit < 0 || result.DeletedCount < int64(limit) {
+				ok, err := matchDocument(document, filter)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					result.DeletedCount++
+					continue
+				}
+			}
+			kept = append(kept, document)
+		}
+
+		return kept, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (m *MemoryMongoDatabaseImpl) bulkWrite(ctx context.Context, collection string, model mongo.WriteModel, index int64, result *mongo.BulkWriteResult) error {
+	switch model := model.(type) {
+	case *mongo.InsertOneModel:
+		document, err := toDocument(model.Document)
+		if err != nil {
+			return err
+		}
+
+		document = withId(document, nil)
+		if err := m.write(ctx, collection, "", func(documents []bson.D) ([]bson.D, error) {
+			return append(documents, document), nil
+		}); err != nil {
+			return err
+		}
+		result.InsertedCount++
+	case *mongo.UpdateOneModel:
+		before, after, err := m.updateOne(ctx, collection, model.Filter, model.Update, model.Sort, model.Upsert != nil && *model.Upsert)
+		if err != nil {
+			return err
+		}
+		addUpdateResult(result, updateResult(before, after), index)
+	case *mongo.UpdateManyModel:
+		filter, err := toDocument(model.Filter)
+		if err != nil {
+			return err
+		}
+		operators, err := toUpdate(model.Update)
+		if err != nil {
+			return err
+		}
+		updated, err := m.updateMany(ctx, collection, filter, operators)
+		if err != nil {
+			return err
+		}
+		addUpdateResult(result, updated, index)
+	case *mongo.ReplaceOneModel:
+		filter, err := toDocument(model.Filter)
+		if err != nil {
+			return err
+		}
+		replacement, err := toReplacement(model.Replacement)
+		if err != nil {
+			return err
+		}
+		replaced, err := m.replaceOne(ctx, collection, filter, replacement, model.Sort, model.Upsert != nil && *model.Upsert)
+		if err != nil {
+			return err
+		}
+		addUpdateResult(result, replaced, index)
+	case *mongo.DeleteOneModel:
+		deleted, err := m.delete(ctx, collection, model.Filter, 1)
+		if err != nil {
+			return err
+		}
+		result.DeletedCount += deleted.DeletedCount
+	case *mongo.DeleteManyModel:
+		deleted, err := m.delete(ctx, collection, model.Filter, -1)
+		if err != nil {
+			return err
+		}
+		result.DeletedCount += deleted.DeletedCount
+	default:
+		return fmt.Errorf("write model %T: %w", model, ErrMemoryMongoUnsupported)
+	}
+
+	return nil
+}
+
+func (m *MemoryMongoDatabaseImpl) write(ctx context.Context, collection string, operation string, fn func(documents []bson.D) ([]bson.D, error)) error {
+	current := m.collection(collection)
+
+	documents, err := fn(append([]bson.D{}, current.documents...))
+	if err != nil {
+		return err
+	}
+
+	if err := checkUnique(collection, documents, current.indexes); err != nil {
+		return err
+	}
+
+	previous := current.documents
+	current.documents = documents
+	m.publish(ctx, collection, operation, previous, documents)
+
+	return nil
+}
+
+func (m *MemoryMongoDatabaseImpl) publish(ctx context.Context, collection string, operation string, previous []bson.D, documents []bson.D) {
+	if len(m.streams) == 0 {
+		return
+	}
+
+	before := map[string]bson.D{}
+	for _, document := range previous {
+		id, _ := lookupPath(document, "_id")
+		before[valueKey(id)] = document
+	}
+
+	events := []bson.D{}
+	for _, document := range documents {
+		id, _ := lookupPath(document, "_id")
+		key := valueKey(id)
+
+		existing, ok := before[key]
+		delete(before, key)
+		switch {
+		case !ok:
+			events = append(events, m.changeEvent(collection, "insert", id, document))
+		case !documentsEqual(existing, document):
+			events = append(events, m.changeEvent(collection, operation, id, document))
+		}
+	}
+	for _, document := range previous {
+		id, _ := lookupPath(document, "_id")
+		if _, ok := before[valueKey(id)]; ok {
+			events = append(events, m.changeEvent(collection, "delete", id, nil))
+		}
+	}
+
+	for _, event := range events {
+		change := memoryChangeEvent{collection: collection, event: event}
+		if ctx.Value(memoryTransactionKey{}) == m {
+			m.pending = append(m.pending, change)
+			continue
+		}
+		m.deliver(change)
+	}
+}
+
+func (m *MemoryMongoDatabaseImpl) changeEvent(collection string, operation string, id interface{}, document bson.D) bson.D {
+	m.sequence++
+
+	event := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: fmt.Sprint(m.sequence)}}},
+		{Key: "operationType", Value: operation},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "memory"}, {Key: "coll", Value: collection}}},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: id}}},
+	}
+	if document != nil {
+		event = append(event, bson.E{Key: "fullDocument", Value: document})
+	}
+
+	return event
+}
+
+func (m *MemoryMongoDatabaseImpl) deliver(change memoryChangeEvent) {
+	for stream := range m.streams {
+		if stream.collection == change.collection {
+			stream.push(change.event)
+		}
+	}
+}
+
+func (s *memoryChangeStream) push(event bson.D) {
+	for _, filter := range s.filters {
+		if ok, err := matchDocument(event, filter); err != nil || !ok {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *memoryChangeStream) Next(ctx context.Context) bool {
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			s.current, s.queue = s.queue[0], s.queue[1:]
+			s.mu.Unlock()
+			return true
+		}
+		closed := s.closed
+		s.mu.Unlock()
+
+		if closed {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.err = ctx.Err()
+			s.mu.Unlock()
+			return false
+		case <-s.notify:
+		}
+	}
+}
+
+func (s *memoryChangeStream) Decode(val interface{}) error {
+	data, err := bson.Marshal(s.current)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(data, val)
+}
+
+func (s *memoryChangeStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *memoryChangeStream) Close(ctx context.Context) error {
+	unlock := s.database.lock(ctx)
+	delete(s.database.streams, s)
+	unlock()
+
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
@@ -0,0 +1,1177 @@
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+func checkUnique(collection string, documents []bson.D, indexes []memoryIndex) error {
+	indexes = append([]memoryIndex{{name: "_id_", keys: []string{"_id"}, unique: true}}, indexes...)
+
+	for _, index := range indexes {
+		if !index.unique {
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, document := range documents {
+			values := bson.A{}
+			for _, key := range index.keys {
+				value, _ := lookupPath(document, key)
+				values = append(values, normalizeValue(value))
+			}
+
+			key := fmt.Sprintf("%#v", values)
+			if seen[key] {
+				return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+					Code:    11000,
+					Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: %s", collection, index.name),
+				}}}
+			}
+			seen[key] = true
+		}
+	}
+
+	return nil
+}
+
+func firstMatch(documents []bson.D, filter bson.D, sortBy interface{}) (int, error) {
+	order := make([]int, len(documents))
+	for i := range order {
+		order[i] = i
+	}
+
+	if sortBy != nil {
+		keys, err := toDocument(sortBy)
+		if err != nil {
+			return -1, err
+		}
+
+		sort.SliceStable(order, func(i, j int) bool {
+			return compareDocuments(documents[order[i]], documents[order[j]], keys) < 0
+		})
+	}
+
+	for _, i := range order {
+		ok, err := matchDocument(documents[i], filter)
+		if err != nil {
+			return -1, err
+		}
+		if ok {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+func matchDocument(document bson.D, filter bson.D) (bool, error) {
+	for _, element := range filter {
+		switch element.Key {
+		case "$and", "$or", "$nor":
+			clauses, err := toDocuments(element.Value)
+			if err != nil {
+				return false, err
+			}
+
+			matched := 0
+			for _, clause := range clauses {
+				ok, err := matchDocument(document, clause)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					matched++
+				}
+			}
+
+			switch {
+			case element.Key == "$and" && matched != len(clauses),
+				element.Key == "$or" && matched == 0,
+				element.Key == "$nor" && matched != 0:
+				return false, nil
+			}
+		default:
+			if strings.HasPrefix(element.Key, "$") {
+				return false, fmt.Errorf("query operator %s: %w", element.Key, ErrMemoryMongoUnsupported)
+			}
+
+			value, found := lookupPath(document, element.Key)
+			ok, err := matchValue(value, found, element.Value)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func matchValue(value interface{}, found bool, condition interface{}) (bool, error) {
+	if !isOperatorDocument(condition) {
+		return equalsValue(value, found, condition), nil
+	}
+
+	for _, operator := range condition.(bson.D) {
+		var ok bool
+		switch operator.Key {
+		case "$eq":
+			ok = equalsValue(value, found, operator.Value)
+		case "$ne":
+			ok = !equalsValue(value, found, operator.Value)
+		case "$gt", "$gte", "$lt", "$lte":
+			result, comparable := compareValues(value, operator.Value)
+			if !found || !comparable {
+				return false, nil
+			}
+			ok = (operator.Key == "$gt" && result > 0) ||
+				(operator.Key == "$gte" && result >= 0) ||
+				(operator.Key == "$lt" && result < 0) ||
+				(operator.Key == "$lte" && result <= 0)
+		case "$in", "$nin":
+			candidates, isArray := operator.Value.(bson.A)
+			if !isArray {
+				return false, fmt.Errorf("%s needs an array", operator.Key)
+			}
+			for _, candidate := range candidates {
+				if equalsValue(value, found, candidate) {
+					ok = true
+					break
+				}
+			}
+			if operator.Key == "$nin" {
+				ok = !ok
+			}
+		case "$exists":
+			exists, _ := operator.Value.(bool)
+			ok = found == exists
+		case "$regex":
+			var err error
+			if ok, err = matchRegex(value, operator.Value, condition.(bson.D)); err != nil {
+				return false, err
+			}
+		case "$options":
+			ok = true
+		case "$not":
+			matched, err := matchValue(value, found, operator.Value)
+			if err != nil {
+				return false, err
+			}
+			ok = !matched
+		case "$size":
+			size, isNumber := toInt64(operator.Value)
+			values, isArray := value.(bson.A)
+			ok = isNumber && isArray && int64(len(values)) == size
+		case "$all":
+			candidates, isArray := operator.Value.(bson.A)
+			if !isArray {
+				return false, fmt.Errorf("$all needs an array")
+			}
+			ok = true
+			for _, candidate := range candidates {
+				if !equalsValue(value, found, candidate) {
+					ok = false
+					break
+				}
+			}
+		case "$elemMatch":
+			filter, isDocument := operator.Value.(bson.D)
+			values, isArray := value.(bson.A)
+			if !isDocument {
+				return false, fmt.Errorf("$elemMatch needs a document")
+			}
+			for _, element := range values {
+				matched, err := matchElement(element, filter)
+				if err != nil {
+					return false, err
+				}
+				if matched {
+					ok = true
+					break
+				}
+			}
+			ok = ok && isArray
+		default:
+			return false, fmt.Errorf("query operator %s: %w", operator.Key, ErrMemoryMongoUnsupported)
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchRegex(value interface{}, pattern interface{}, condition bson.D) (bool, error) {
+	text, isString := value.(string)
+	if !isString {
+		return false, nil
+	}
+
+	var expression, flags string
+	switch pattern := pattern.(type) {
+	case string:
+		expression = pattern
+	case bson.Regex:
+		expression, flags = pattern.Pattern, pattern.Options
+	default:
+		return false, fmt.Errorf("$regex needs a string")
+	}
+	for _, element := range condition {
+		if element.Key == "$options" {
+			flags, _ = element.Value.(string)
+		}
+	}
+
+	var prefix string
+	for _, flag := range flags {
+		switch flag {
+		case 'i', 'm', 's':
+			prefix += string(flag)
+		default:
+			return false, fmt.Errorf("$regex option %c: %w", flag, ErrMemoryMongoUnsupported)
+		}
+	}
+	if prefix != "" {
+		expression = "(?" + prefix + ")" + expression
+	}
+
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return false, fmt.Errorf("invalid $regex: %w", err)
+	}
+
+	return compiled.MatchString(text), nil
+}
+
+func matchElement(element interface{}, filter bson.D) (bool, error) {
+	if isOperatorDocument(filter) {
+		return matchValue(element, true, filter)
+	}
+
+	document, ok := element.(bson.D)
+	if !ok {
+		return false, nil
+	}
+
+	return matchDocument(document, filter)
+}
+
+func equalsValue(value interface{}, found bool, target interface{}) bool {
+	if !found {
+		return target == nil
+	}
+
+	if values, ok := value.(bson.A); ok {
+		if _, targetIsArray := target.(bson.A); !targetIsArray {
+			for _, element := range values {
+				if equalsValue(element, true, target) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	if result, comparable := compareValues(value, target); comparable {
+		return result == 0
+	}
+
+	return reflect.DeepEqual(normalizeValue(value), normalizeValue(target))
+}
+
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	a, b = normalizeValue(a), normalizeValue(b)
+
+	switch a := a.(type) {
+	case nil:
+		return 0, b == nil
+	case float64:
+		if b, ok := b.(float64); ok {
+			return compareOrdered(a, b), true
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case b:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case bson.DateTime:
+		if b, ok := b.(bson.DateTime); ok {
+			return compareOrdered(a, b), true
+		}
+	case bson.ObjectID:
+		if b, ok := b.(bson.ObjectID); ok {
+			return bytes.Compare(a[:], b[:]), true
+		}
+	}
+
+	return 0, false
+}
+
+func compareOrdered[T float64 | bson.DateTime](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareDocuments(a bson.D, b bson.D, keys bson.D) int {
+	for _, key := range keys {
+		direction, _ := toInt64(key.Value)
+
+		left, leftFound := lookupPath(a, key.Key)
+		right, rightFound := lookupPath(b, key.Key)
+
+		result := 0
+		switch {
+		case !leftFound && !rightFound:
+		case !leftFound:
+			result = -1
+		case !rightFound:
+			result = 1
+		default:
+			if comparison, ok := compareValues(left, right); ok {
+				result = comparison
+			} else {
+				result = compareOrdered(float64(typeRank(left)), float64(typeRank(right)))
+			}
+		}
+
+		if result != 0 {
+			if direction < 0 {
+				return -result
+			}
+			return result
+		}
+	}
+
+	return 0
+}
+
+func typeRank(value interface{}) int {
+	switch normalizeValue(value).(type) {
+	case nil:
+		return 0
+	case float64:
+		return 1
+	case string:
+		return 2
+	case bson.D:
+		return 3
+	case bson.A:
+		return 4
+	case bson.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case bson.DateTime:
+		return 7
+	default:
+		return 8
+	}
+}
+
+func sortDocuments(documents []bson.D, sortBy interface{}) error {
+	keys, err := toDocument(sortBy)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		return compareDocuments(documents[i], documents[j], keys) < 0
+	})
+
+	return nil
+}
+
+func skipLimit(documents []bson.D, skip *int64, limit *int64) []bson.D {
+	if skip != nil && *skip > 0 {
+		if *skip >= int64(len(documents)) {
+			return []bson.D{}
+		}
+		documents = documents[*skip:]
+	}
+
+	if limit != nil && *limit > 0 && *limit < int64(len(documents)) {
+		documents = documents[:*limit]
+	}
+
+	return documents
+}
+
+func projectDocuments(documents []bson.D, projection interface{}) ([]bson.D, error) {
+	projected := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		document, err := projectDocument(document, projection)
+		if err != nil {
+			return nil, err
+		}
+		projected = append(projected, document)
+	}
+
+	return projected, nil
+}
+
+func projectDocument(document bson.D, projection interface{}) (bson.D, error) {
+	if projection == nil {
+		return document, nil
+	}
+
+	fields, err := toDocument(projection)
+	if err != nil {
+		return nil, err
+	}
+
+	include, excludeId := false, false
+	selected := map[string]bool{}
+	for _, field := range fields {
+		enabled := isTruthy(field.Value)
+		if field.Key == "_id" {
+			excludeId = !enabled
+			continue
+		}
+		if enabled {
+			include = true
+		}
+		selected[field.Key] = enabled
+	}
+
+	projected := bson.D{}
+	for _, element := range document {
+		keep := !include || selected[element.Key]
+		if element.Key == "_id" {
+			keep = !excludeId
+		} else if !include {
+			if enabled, ok := selected[element.Key]; ok && !enabled {
+				keep = false
+			}
+		}
+
+		if keep {
+			projected = append(projected, element)
+		}
+	}
+
+	return projected, nil
+}
+
+func applyUpdate(document bson.D, operators bson.D, insert bool) (bson.D, error) {
+	updated := cloneDocument(document)
+
+	for _, operator := range operators {
+		fields, ok := operator.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a document", operator.Key)
+		}
+
+		for _, field := range fields {
+			if field.Key == "_id" && operator.Key != "$setOnInsert" && !insert {
+				current, _ := lookupPath(updated, "_id")
+				if !equalsValue(current, true, field.Value) {
+					return nil, fmt.Errorf("the _id field cannot be modified")
+				}
+			}
+
+			switch operator.Key {
+			case "$set":
+				updated = setPath(updated, field.Key, field.Value)
+			case "$setOnInsert":
+				if insert {
+					updated = setPath(updated, field.Key, field.Value)
+				}
+			case "$unset":
+				updated = unsetPath(updated, field.Key)
+			case "$inc":
+				current, found := lookupPath(updated, field.Key)
+				if !found {
+					updated = setPath(updated, field.Key, field.Value)
+					continue
+				}
+
+				sum, err := addNumbers(current, field.Value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to $inc %s: %w", field.Key, err)
+				}
+				updated = setPath(updated, field.Key, sum)
+			case "$push":
+				current, found := lookupPath(updated, field.Key)
+				values, isArray := current.(bson.A)
+				if found && !isArray {
+					return nil, fmt.Errorf("failed to $push %s: not an array", field.Key)
+				}
+				updated = setPath(updated, field.Key, append(append(bson.A{}, values...), field.Value))
+			case "$currentDate":
+				updated = setPath(updated, field.Key, bson.NewDateTimeFromTime(time.Now()))
+			case "$min", "$max":
+				current, found := lookupPath(updated, field.Key)
+				result, comparable := compareValues(field.Value, current)
+				if !found || (comparable && ((operator.Key == "$min" && result < 0) || (operator.Key == "$max" && result > 0))) {
+					updated = setPath(updated, field.Key, field.Value)
+				}
+			case "$addToSet":
+				current, found := lookupPath(updated, field.Key)
+				values, isArray := current.(bson.A)
+				if found && !isArray {
+					return nil, fmt.Errorf("failed to $addToSet %s: not an array", field.Key)
+				}
+				if !equalsValue(values, true, field.Value) {
+					updated = setPath(updated, field.Key, append(append(bson.A{}, values...), field.Value))
+				}
+			case "$pull":
+				current, found := lookupPath(updated, field.Key)
+				values, isArray := current.(bson.A)
+				if !found {
+					continue
+				}
+				if !isArray {
+					return nil, fmt.Errorf("failed to $pull %s: not an array", field.Key)
+				}
+
+				kept := bson.A{}
+				for _, element := range values {
+					var matched bool
+					var err error
+					if filter, isDocument := field.Value.(bson.D); isDocument {
+						matched, err = matchElement(element, filter)
+					} else {
+						matched = equalsValue(element, true, field.Value)
+					}
+					if err != nil {
+						return nil, err
+					}
+					if !matched {
+						kept = append(kept, element)
+					}
+				}
+				updated = setPath(updated, field.Key, kept)
+			default:
+				return nil, fmt.Errorf("update operator %s: %w", operator.Key, ErrMemoryMongoUnsupported)
+			}
+		}
+	}
+
+	return updated, nil
+}
+
+func addNumbers(a interface{}, b interface{}) (interface{}, error) {
+	switch a := a.(type) {
+	case int32:
+		if b, ok := b.(int32); ok {
+			return a + b, nil
+		}
+	case int64:
+		switch b := b.(type) {
+		case int32:
+			return a + int64(b), nil
+		case int64:
+			return a + b, nil
+		}
+	}
+
+	left, leftOk := normalizeValue(a).(float64)
+	right, rightOk := normalizeValue(b).(float64)
+	if !leftOk || !rightOk {
+		return nil, fmt.Errorf("cannot add %T and %T", a, b)
+	}
+
+	return left + right, nil
+}
+
+func lookupPath(document bson.D, path string) (interface{}, bool) {
+	head, rest, nested := strings.Cut(path, ".")
+
+	for _, element := range document {
+		if element.Key != head {
+			continue
+		}
+
+		if !nested {
+			return element.Value, true
+		}
+
+		child, ok := element.Value.(bson.D)
+		if !ok {
+			return nil, false
+		}
+
+		return lookupPath(child, rest)
+	}
+
+	return nil, false
+}
+
+func setPath(document bson.D, path string, value interface{}) bson.D {
+	head, rest, nested := strings.Cut(path, ".")
+
+	for i, element := range document {
+		if element.Key != head {
+			continue
+		}
+
+		if nested {
+			child, _ := element.Value.(bson.D)
+			document[i].Value = setPath(cloneDocument(child), rest, value)
+		} else {
+			document[i].Value = value
+		}
+
+		return document
+	}
+
+	if nested {
+		return append(document, bson.E{Key: head, Value: setPath(bson.D{}, rest, value)})
+	}
+
+	return append(document, bson.E{Key: head, Value: value})
+}
+
+func unsetPath(document bson.D, path string) bson.D {
+	head, rest, nested := strings.Cut(path, ".")
+
+	for i, element := range document {
+		if element.Key != head {
+			continue
+		}
+
+		if !nested {
+			return append(document[:i:i], document[i+1:]...)
+		}
+
+		if child, ok := element.Value.(bson.D); ok {
+			document[i].Value = unsetPath(cloneDocument(child), rest)
+		}
+
+		return document
+	}
+
+	return document
+}
+
+func equalityValue(filter bson.D, key string) (interface{}, bool) {
+	for _, element := range filter {
+		if element.Key == key && !isOperatorDocument(element.Value) {
+			return element.Value, true
+		}
+
+		if element.Key == "$and" {
+			clauses, _ := toDocuments(element.Value)
+			for _, clause := range clauses {
+				if value, ok := equalityValue(clause, key); ok {
+					return value, true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func withId(document bson.D, id interface{}) bson.D {
+	if id == nil {
+		if current, ok := lookupPath(document, "_id"); ok {
+			id = current
+		} else {
+			id = bson.NewObjectID()
+		}
+	}
+
+	result := bson.D{{Key: "_id", Value: id}}
+	for _, element := range document {
+		if element.Key != "_id" {
+			result = append(result, element)
+		}
+	}
+
+	return result
+}
+
+func isOperatorDocument(value interface{}) bool {
+	document, ok := value.(bson.D)
+	return ok && len(document) > 0 && strings.HasPrefix(document[0].Key, "$")
+}
+
+func isTruthy(value interface{}) bool {
+	switch value := normalizeValue(value).(type) {
+	case bool:
+		return value
+	case float64:
+		return value != 0
+	default:
+		return value != nil
+	}
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	number, ok := normalizeValue(value).(float64)
+	return int64(number), ok
+}
+
+func normalizeValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	case time.Time:
+		return bson.NewDateTimeFromTime(value)
+	default:
+		return value
+	}
+}
+
+func documentsEqual(a bson.D, b bson.D) bool {
+	left, leftErr := bson.Marshal(a)
+	right, rightErr := bson.Marshal(b)
+
+	return leftErr == nil && rightErr == nil && bytes.Equal(left, right)
+}
+
+func cloneDocument(document bson.D) bson.D {
+	return append(bson.D{}, document...)
+}
+
+func toReplacement(data interface{}) (bson.D, error) {
+	replacement, err := toDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, element := range replacement {
+		if strings.HasPrefix(element.Key, "$") {
+			return nil, fmt.Errorf("replacement document must not contain update operators")
+		}
+	}
+
+	return replacement, nil
+}
+
+func toUpdate(update interface{}) (bson.D, error) {
+	if _, ok := update.(bson.D); !ok && isSequence(update) {
+		return nil, fmt.Errorf("pipeline updates: %w", ErrMemoryMongoUnsupported)
+	}
+
+	operators, err := toDocument(update)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(operators) == 0 {
+		return nil, fmt.Errorf("update document must not be empty")
+	}
+
+	for _, operator := range operators {
+		if !strings.HasPrefix(operator.Key, "$") {
+			return nil, fmt.Errorf("update document must contain only update operators")
+		}
+	}
+
+	return operators, nil
+}
+
+func isSequence(value interface{}) bool {
+	kind := reflect.ValueOf(value).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func toDocument(value interface{}) (bson.D, error) {
+	if value == nil {
+		return bson.D{}, nil
+	}
+
+	if document, ok := value.(bson.D); ok {
+		value = map[string]interface{}{"v": document}
+	} else {
+		value = map[string]interface{}{"v": value}
+	}
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+
+	var wrapper struct {
+		V bson.D `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	if wrapper.V == nil {
+		return bson.D{}, nil
+	}
+
+	return wrapper.V, nil
+}
+
+func toDocuments(value interface{}) ([]bson.D, error) {
+	data, err := bson.Marshal(map[string]interface{}{"v": value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode documents: %w", err)
+	}
+
+	var wrapper struct {
+		V []bson.D `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to decode documents: %w", err)
+	}
+
+	return wrapper.V, nil
+}
+
+func newMemoryCursor(documents []bson.D) (*mongo.Cursor, error) {
+	values := make([]interface{}, 0, len(documents))
+	for _, document := range documents {
+		values = append(values, document)
+	}
+
+	return mongo.NewCursorFromDocuments(values, nil, nil)
+}
+
+func listOptions[T any](opts []options.Lister[T]) (*T, error) {
+	args := new(T)
+	for _, opt := range opts {
+		if opt == nil || reflect.ValueOf(opt).IsNil() {
+			continue
+		}
+
+		for _, setter := range opt.List() {
+			if err := setter(args); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return args, nil
+}
+
+func addUpdateResult(result *mongo.BulkWriteResult, updateResult *mongo.UpdateResult, index int64) {
+	result.MatchedCount += updateResult.MatchedCount
+	result.ModifiedCount += updateResult.ModifiedCount
+	result.UpsertedCount += updateResult.UpsertedCount
+	if updateResult.UpsertedID != nil {
+		result.UpsertedIDs[index] = updateResult.UpsertedID
+	}
+}
+
+func updateResult(before bson.D, after bson.D) *mongo.UpdateResult {
+	result := &mongo.UpdateResult{Acknowledged: true}
+	switch {
+	case before != nil:
+		result.MatchedCount = 1
+		if !documentsEqual(before, after) {
+			result.ModifiedCount = 1
+		}
+	case after != nil:
+		result.UpsertedCount = 1
+		result.UpsertedID, _ = lookupPath(after, "_id")
+	}
+
+	return result
+}
+
+func valueKey(value interface{}) string {
+	return fmt.Sprintf("%#v", normalizeValue(value))
+}
+
+func aggregateDocuments(documents []bson.D, stages []bson.D) ([]bson.D, error) {
+	for _, stage := range stages {
+		if len(stage) != 1 {
+			return nil, fmt.Errorf("aggregation stage must have exactly one operator")
+		}
+
+		var err error
+		operator, value := stage[0].Key, stage[0].Value
+		switch operator {
+		case "$match":
+			var filter bson.D
+			if filter, err = toDocument(value); err != nil {
+				return nil, err
+			}
+
+			matched := []bson.D{}
+			for _, document := range documents {
+				ok, err := matchDocument(document, filter)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					matched = append(matched, document)
+				}
+			}
+			documents = matched
+		case "$sort":
+			documents = append([]bson.D{}, documents...)
+			err = sortDocuments(documents, value)
+		case "$skip", "$limit":
+			count, ok := toInt64(value)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a number", operator)
+			}
+			if operator == "$skip" {
+				documents = skipLimit(documents, &count, nil)
+			} else {
+				documents = skipLimit(documents, nil, &count)
+			}
+		case "$project":
+			documents, err = projectDocuments(documents, value)
+		case "$addFields", "$set":
+			documents, err = addFields(documents, value)
+		case "$count":
+			field, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("$count must be a field name")
+			}
+			documents = []bson.D{{{Key: field, Value: int32(len(documents))}}}
+		case "$unwind":
+			documents, err = unwindDocuments(documents, value)
+		case "$group":
+			documents, err = groupDocuments(documents, value)
+		default:
+			return nil, fmt.Errorf("aggregation stage %s: %w", operator, ErrMemoryMongoUnsupported)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return documents, nil
+}
+
+func addFields(documents []bson.D, value interface{}) ([]bson.D, error) {
+	fields, err := toDocument(value)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		updated := cloneDocument(document)
+		for _, field := range fields {
+			evaluated, err := evaluateExpression(document, field.Value)
+			if err != nil {
+				return nil, err
+			}
+			updated = setPath(updated, field.Key, evaluated)
+		}
+		results = append(results, updated)
+	}
+
+	return results, nil
+}
+
+func unwindDocuments(documents []bson.D, value interface{}) ([]bson.D, error) {
+	path, ok := value.(string)
+	if options, isDocument := value.(bson.D); isDocument {
+		path, ok = "", false
+		for _, option := range options {
+			if option.Key != "path" {
+				return nil, fmt.Errorf("$unwind option %s: %w", option.Key, ErrMemoryMongoUnsupported)
+			}
+			path, ok = option.Value.(string)
+		}
+	}
+	if !ok || !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("$unwind needs a field path")
+	}
+	path = path[1:]
+
+	results := []bson.D{}
+	for _, document := range documents {
+		current, found := lookupPath(document, path)
+		values, isArray := current.(bson.A)
+		switch {
+		case !found || current == nil:
+		case !isArray:
+			results = append(results, document)
+		default:
+			for _, element := range values {
+				results = append(results, setPath(cloneDocument(document), path, element))
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func groupDocuments(documents []bson.D, value interface{}) ([]bson.D, error) {
+	fields, err := toDocument(value)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := lookupPath(fields, "_id")
+	if !ok {
+		return nil, fmt.Errorf("$group needs an _id")
+	}
+
+	type group struct {
+		id        interface{}
+		documents []bson.D
+	}
+	order := []string{}
+	groups := map[string]*group{}
+	for _, document := range documents {
+		groupId, err := evaluateExpression(document, id)
+		if err != nil {
+			return nil, err
+		}
+
+		key := valueKey(groupId)
+		if _, ok := groups[key]; !ok {
+			groups[key] = &group{id: groupId}
+			order = append(order, key)
+		}
+		groups[key].documents = append(groups[key].documents, document)
+	}
+
+	results := make([]bson.D, 0, len(order))
+	for _, key := range order {
+		current := groups[key]
+
+		result := bson.D{{Key: "_id", Value: current.id}}
+		for _, field := range fields {
+			if field.Key == "_id" {
+				continue
+			}
+
+			accumulator, ok := field.Value.(bson.D)
+			if !ok || len(accumulator) != 1 {
+				return nil, fmt.Errorf("$group field %s needs exactly one accumulator", field.Key)
+			}
+
+			accumulated, err := accumulate(current.documents, accumulator[0].Key, accumulator[0].Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to accumulate %s: %w", field.Key, err)
+			}
+			result = append(result, bson.E{Key: field.Key, Value: accumulated})
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func accumulate(documents []bson.D, operator string, expression interface{}) (interface{}, error) {
+	values := bson.A{}
+	for _, document := range documents {
+		value, err := evaluateExpression(document, expression)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	switch operator {
+	case "$sum", "$avg":
+		var sum interface{} = int32(0)
+		count := 0
+		for _, value := range values {
+			if _, isNumber := normalizeValue(value).(float64); !isNumber {
+				continue
+			}
+
+			var err error
+			if sum, err = addNumbers(sum, value); err != nil {
+				return nil, err
+			}
+			count++
+		}
+
+		if operator == "$sum" {
+			return sum, nil
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		total, _ := normalizeValue(sum).(float64)
+		return total / float64(count), nil
+	case "$min", "$max":
+		var result interface{}
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+			comparison, comparable := compareValues(value, result)
+			if result == nil || (comparable && ((operator == "$min" && comparison < 0) || (operator == "$max" && comparison > 0))) {
+				result = value
+			}
+		}
+		return result, nil
+	case "$first", "$last":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		if operator == "$first" {
+			return values[0], nil
+		}
+		return values[len(values)-1], nil
+	case "$push":
+		return values, nil
+	case "$addToSet":
+		unique := bson.A{}
+		for _, value := range values {
+			if !equalsValue(unique, true, value) {
+				unique = append(unique, value)
+			}
+		}
+		return unique, nil
+	default:
+		return nil, fmt.Errorf("accumulator %s: %w", operator, ErrMemoryMongoUnsupported)
+	}
+}
+
+func evaluateExpression(document bson.D, expression interface{}) (interface{}, error) {
+	switch expression := expression.(type) {
+	case string:
+		if strings.HasPrefix(expression, "$") {
+			value, _ := lookupPath(document, expression[1:])
+			return value, nil
+		}
+		return expression, nil
+	case bson.D:
+		if isOperatorDocument(expression) {
+			return nil, fmt.Errorf("expression operator %s: %w", expression[0].Key, ErrMemoryMongoUnsupported)
+		}
+
+		result := bson.D{}
+		for _, element := range expression {
+			value, err := evaluateExpression(document, element.Value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, bson.E{Key: element.Key, Value: value})
+		}
+		return result, nil
+	default:
+		return expression, nil
+	}
+}
@@ -0,0 +1,489 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"testing"
+	"time"
+)
+
+type memoryMongoItem struct {
+	Id       string     `bson:"_id,omitempty"`
+	Group    string     `bson:"group"`
+	Rank     int        `bson:"rank"`
+	Due      *time.Time `bson:"due,omitempty"`
+	Orphaned *bool      `bson:"orphaned,omitempty"`
+}
+
+func seedMemoryMongo(t *testing.T, db MongoDatabase, items ...memoryMongoItem) {
+	t.Helper()
+
+	for _, item := range items {
+		if _, err := db.ReplaceOne(context.Background(), "items", map[string]interface{}{"_id": item.Id}, item, options.Replace().SetUpsert(true)); err != nil {
+			t.Fatalf("replace %s: %v", item.Id, err)
+		}
+	}
+}
+
+func findMemoryMongoIds(t *testing.T, db MongoDatabase, query map[string]interface{}, opts ...options.Lister[options.FindOptions]) []string {
+	t.Helper()
+
+	cursor, err := db.Find(context.Background(), "items", query, opts...)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+
+	var items []memoryMongoItem
+	if err := cursor.All(context.Background(), &items); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	ids := []string{}
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+
+	return ids
+}
+
+func TestMemoryMongoReplaceOneUpserts(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	result, err := db.ReplaceOne(ctx, "items", map[string]interface{}{"_id": "a"}, memoryMongoItem{Group: "x", Rank: 1})
+	if err != nil || result.MatchedCount != 0 || result.UpsertedCount != 0 {
+		t.Fatalf("got result %+v and error %v without upsert", result, err)
+	}
+
+	result, err = db.ReplaceOne(ctx, "items", map[string]interface{}{"_id": "a"}, memoryMongoItem{Group: "x", Rank: 1}, options.Replace().SetUpsert(true))
+	if err != nil || result.UpsertedCount != 1 || result.UpsertedID != "a" {
+		t.Fatalf("got result %+v and error %v", result, err)
+	}
+
+	result, err = db.ReplaceOne(ctx, "items", map[string]interface{}{"_id": "a"}, memoryMongoItem{Id: "a", Group: "y", Rank: 2})
+	if err != nil || result.MatchedCount != 1 || result.ModifiedCount != 1 {
+		t.Fatalf("got result %+v and error %v", result, err)
+	}
+
+	var item memoryMongoItem
+	if err := db.FindOne(ctx, "items", map[string]interface{}{"_id": "a"}).Decode(&item); err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if item.Group != "y" || item.Rank != 2 {
+		t.Fatalf("got %+v", item)
+	}
+
+	if err := db.FindOne(ctx, "items", map[string]interface{}{"_id": "missing"}).Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("got %v, want no documents", err)
+	}
+}
+
+func TestMemoryMongoFindSortsLimitsAndProjects(t *testing.T) {
+	db := NewMemoryMongoDatabase()
+	seedMemoryMongo(t, db,
+		memoryMongoItem{Id: "a", Group: "x", Rank: 3},
+		memoryMongoItem{Id: "b", Group: "y", Rank: 1},
+		memoryMongoItem{Id: "c", Group: "x", Rank: 2},
+		memoryMongoItem{Id: "d", Group: "z", Rank: 4},
+	)
+
+	ids := findMemoryMongoIds(t, db, map[string]interface{}{"group": map[string]interface{}{"$in": []string{"x", "y"}}}, options.Find().SetSort(bson.D{{Key: "rank", Value: -1}}).SetLimit(2))
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "c" {
+		t.Fatalf("got %v", ids)
+	}
+
+	cursor, err := db.Find(context.Background(), "items", map[string]interface{}{"rank": map[string]interface{}{"$lt": 2}}, options.Find().SetProjection(map[string]interface{}{"rank": 1}))
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+
+	var documents []bson.D
+	if err := cursor.All(context.Background(), &documents); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(documents) != 1 || len(documents[0]) != 2 || documents[0][0].Key != "_id" || documents[0][1].Key != "rank" {
+		t.Fatalf("got %v", documents)
+	}
+}
+
+func TestMemoryMongoFindOneAndUpdateClaimsEarliestMatch(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	early, late := now.Add(-time.Minute), now.Add(time.Minute)
+	seedMemoryMongo(t, db,
+		memoryMongoItem{Id: "future", Group: "pending", Due: &late},
+		memoryMongoItem{Id: "due", Group: "pending", Due: &early},
+		memoryMongoItem{Id: "done", Group: "delivered"},
+	)
+
+	query := map[string]interface{}{
+		"group": "pending",
+		"$or": []interface{}{
+			map[string]interface{}{"due": map[string]interface{}{"$lte": now}},
+			map[string]interface{}{"due": map[string]interface{}{"$exists": false}},
+		},
+	}
+	update := map[string]interface{}{"$set": map[string]interface{}{"due": late}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "due", Value: 1}}).SetReturnDocument(options.After)
+
+	var item memoryMongoItem
+	if err := db.FindOneAndUpdate(ctx, "items", query, update, opts).Decode(&item); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if item.Id != "due" || !item.Due.Equal(late) {
+		t.Fatalf("got %+v", item)
+	}
+
+	if err := db.FindOneAndUpdate(ctx, "items", query, update, opts).Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("got %v, want no documents", err)
+	}
+}
+
+func TestMemoryMongoUpdatesAndDeletesMatches(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	orphaned := true
+	seedMemoryMongo(t, db,
+		memoryMongoItem{Id: "a"},
+		memoryMongoItem{Id: "b", Orphaned: &orphaned},
+		memoryMongoItem{Id: "c"},
+	)
+
+	result, err := db.UpdateMany(ctx, "items", map[string]interface{}{"orphaned": map[string]interface{}{"$exists": false}}, map[string]interface{}{"$set": map[string]interface{}{"orphaned": false}})
+	if err != nil || result.MatchedCount != 2 || result.ModifiedCount != 2 {
+		t.Fatalf("got result %+v and error %v", result, err)
+	}
+
+	if ids := findMemoryMongoIds(t, db, map[string]interface{}{"orphaned": false}); len(ids) != 2 {
+		t.Fatalf("got %v", ids)
+	}
+
+	deleted, err := db.DeleteMany(ctx, "items", map[string]interface{}{"_id": map[string]interface{}{"$in": []string{"a", "b"}}})
+	if err != nil || deleted.DeletedCount != 2 {
+		t.Fatalf("got result %+v and error %v", deleted, err)
+	}
+
+	if ids := findMemoryMongoIds(t, db, nil); len(ids) != 1 || ids[0] != "c" {
+		t.Fatalf("got %v", ids)
+	}
+}
+
+func TestMemoryMongoEnforcesUniqueIndexes(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+	seedMemoryMongo(t, db, memoryMongoItem{Id: "a", Rank: 1})
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "rank", Value: 1}}, Options: options.Index().SetName("rank").SetUnique(true)}
+	if _, err := db.CreateIndex(ctx, "items", index); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	_, err := db.ReplaceOne(ctx, "items", map[string]interface{}{"_id": "b"}, memoryMongoItem{Id: "b", Rank: 1}, options.Replace().SetUpsert(true))
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("got %v, want a duplicate key error", err)
+	}
+
+	if ids := findMemoryMongoIds(t, db, nil); len(ids) != 1 {
+		t.Fatalf("got %v after a rejected write", ids)
+	}
+}
+
+func TestMemoryMongoTransactionRollbackKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	rollback := errors.New("rollback")
+	concurrent := make(chan error, 1)
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := db.ReplaceOne(ctx, "items", map[string]interface{}{"_id": "inside"}, memoryMongoItem{Id: "inside"}, options.Replace().SetUpsert(true)); err != nil {
+			return err
+		}
+
+		go func() {
+			_, err := db.ReplaceOne(context.Background(), "items", map[string]interface{}{"_id": "concurrent"}, memoryMongoItem{Id: "concurrent"}, options.Replace().SetUpsert(true))
+			concurrent <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("got %v, want the transaction error", err)
+	}
+
+	if err := <-concurrent; err != nil {
+		t.Fatalf("concurrent write: %v", err)
+	}
+
+	if ids := findMemoryMongoIds(t, db, nil); len(ids) != 1 || ids[0] != "concurrent" {
+		t.Fatalf("got %v, want only the concurrent write", ids)
+	}
+}
+
+func TestMemoryMongoInsertsCountsAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	inserted, err := db.InsertOne(ctx, "items", memoryMongoItem{Group: "x"})
+	if err != nil {
+		t.Fatalf("insert one: %v", err)
+	}
+	if _, ok := inserted.InsertedID.(bson.ObjectID); !ok {
+		t.Fatalf("got inserted id %v, want a generated object id", inserted.InsertedID)
+	}
+
+	many, err := db.InsertMany(ctx, "items", []interface{}{memoryMongoItem{Id: "a", Group: "x"}, memoryMongoItem{Id: "b", Group: "y"}, memoryMongoItem{Id: "a"}, memoryMongoItem{Id: "c"}})
+	if !mongo.IsDuplicateKeyError(err) || len(many.InsertedIDs) != 2 {
+		t.Fatalf("got result %+v and error %v, want the two documents before the duplicate", many, err)
+	}
+
+	if count, err := db.CountDocuments(ctx, "items", map[string]interface{}{"group": "x"}); err != nil || count != 2 {
+		t.Fatalf("got count %d and error %v", count, err)
+	}
+
+	deleted, err := db.DeleteOne(ctx, "items", map[string]interface{}{"group": "x"})
+	if err != nil || deleted.DeletedCount != 1 {
+		t.Fatalf("got result %+v and error %v", deleted, err)
+	}
+	if count, err := db.CountDocuments(ctx, "items", nil); err != nil || count != 2 {
+		t.Fatalf("got count %d and error %v", count, err)
+	}
+}
+
+func TestMemoryMongoUpdateOneAppliesOperators(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	update := map[string]interface{}{
+		"$inc":         map[string]interface{}{"rank": 2},
+		"$setOnInsert": map[string]interface{}{"group": "created"},
+		"$addToSet":    map[string]interface{}{"tags": "a"},
+	}
+	result, err := db.UpdateOne(ctx, "items", map[string]interface{}{"_id": "a"}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil || result.UpsertedCount != 1 || result.UpsertedID != "a" {
+		t.Fatalf("got result %+v and error %v", result, err)
+	}
+
+	result, err = db.UpdateOne(ctx, "items", map[string]interface{}{"_id": "a"}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil || result.MatchedCount != 1 || result.ModifiedCount != 1 {
+		t.Fatalf("got result %+v and error %v", result, err)
+	}
+
+	if _, err := db.UpdateOne(ctx, "items", map[string]interface{}{"_id": "a"}, map[string]interface{}{
+		"$push":  map[string]interface{}{"tags": "b"},
+		"$max":   map[string]interface{}{"rank": 1},
+		"$unset": map[string]interface{}{"missing": ""},
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := db.UpdateOne(ctx, "items", map[string]interface{}{"_id": "a"}, map[string]interface{}{"$pull": map[string]interface{}{"tags": "a"}}); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+
+	var item struct {
+		Group string   `bson:"group"`
+		Rank  int      `bson:"rank"`
+		Tags  []string `bson:"tags"`
+	}
+	if err := db.FindOne(ctx, "items", map[string]interface{}{"_id": "a"}).Decode(&item); err != nil {
+		t.Fatalf("find one: %v", err)
+	}
+	if item.Group != "created" || item.Rank != 4 || len(item.Tags) != 1 || item.Tags[0] != "b" {
+		t.Fatalf("got %+v", item)
+	}
+
+	if _, err := db.UpdateOne(ctx, "items", map[string]interface{}{"_id": "a"}, map[string]interface{}{"$set": map[string]interface{}{"_id": "b"}}); err == nil {
+		t.Fatal("updated the _id field")
+	}
+}
+
+func TestMemoryMongoFindOneAndUpdateUpserts(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var item memoryMongoItem
+	if err := db.FindOneAndUpdate(ctx, "items", map[string]interface{}{"_id": "a"}, map[string]interface{}{"$set": map[string]interface{}{"group": "x"}}, opts).Decode(&item); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if item.Id != "a" || item.Group != "x" {
+		t.Fatalf("got %+v", item)
+	}
+
+	if err := db.FindOneAndUpdate(ctx, "items", map[string]interface{}{"_id": "b"}, map[string]interface{}{"$set": map[string]interface{}{"group": "x"}}, options.FindOneAndUpdate().SetUpsert(true)).Err(); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("got %v, want no documents before the upsert", err)
+	}
+}
+
+func TestMemoryMongoFindSkipsAndMatchesPatterns(t *testing.T) {
+	db := NewMemoryMongoDatabase()
+	seedMemoryMongo(t, db,
+		memoryMongoItem{Id: "a", Group: "alpha", Rank: 1},
+		memoryMongoItem{Id: "b", Group: "beta", Rank: 2},
+		memoryMongoItem{Id: "c", Group: "Alpine", Rank: 3},
+	)
+
+	query := map[string]interface{}{"group": map[string]interface{}{"$regex": "^al", "$options": "i"}}
+	if ids := findMemoryMongoIds(t, db, query, options.Find().SetSort(map[string]interface{}{"rank": 1}).SetSkip(1)); len(ids) != 1 || ids[0] != "c" {
+		t.Fatalf("got %v", ids)
+	}
+
+	query = map[string]interface{}{"rank": map[string]interface{}{"$not": map[string]interface{}{"$gt": 1}}}
+	if ids := findMemoryMongoIds(t, db, query); len(ids) != 1 || ids[0] != "a" {
+		t.Fatalf("got %v", ids)
+	}
+}
+
+func TestMemoryMongoAggregates(t *testing.T) {
+	db := NewMemoryMongoDatabase()
+	seedMemoryMongo(t, db,
+		memoryMongoItem{Id: "a", Group: "x", Rank: 1},
+		memoryMongoItem{Id: "b", Group: "y", Rank: 2},
+		memoryMongoItem{Id: "c", Group: "x", Rank: 3},
+		memoryMongoItem{Id: "d", Group: "z", Rank: 4},
+	)
+
+	cursor, err := db.Aggregate(context.Background(), "items", []interface{}{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "group", Value: bson.D{{Key: "$in", Value: bson.A{"x", "y"}}}}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$group"},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$rank"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}}}},
+	})
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+
+	var groups []struct {
+		Id    string   `bson:"_id"`
+		Total int      `bson:"total"`
+		Count int      `bson:"count"`
+		Ids   []string `bson:"ids"`
+	}
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Id != "x" || groups[0].Total != 4 || groups[0].Count != 2 || len(groups[0].Ids) != 2 || groups[1].Id != "y" {
+		t.Fatalf("got %+v", groups)
+	}
+
+	cursor, err = db.Aggregate(context.Background(), "items", []interface{}{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "rank", Value: bson.D{{Key: "$gte", Value: 2}}}}}},
+		bson.D{{Key: "$count", Value: "matched"}},
+	})
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+
+	var counts []struct {
+		Matched int `bson:"matched"`
+	}
+	if err := cursor.All(context.Background(), &counts); err != nil || len(counts) != 1 || counts[0].Matched != 3 {
+		t.Fatalf("got %+v and error %v", counts, err)
+	}
+}
+
+func TestMemoryMongoBulkWrites(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+	seedMemoryMongo(t, db, memoryMongoItem{Id: "a", Rank: 1}, memoryMongoItem{Id: "b", Rank: 2})
+
+	result, err := db.BulkWrite(ctx, "items", []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(memoryMongoItem{Id: "c", Rank: 3}),
+		mongo.NewUpdateOneModel().SetFilter(bson.D{{Key: "_id", Value: "a"}}).SetUpdate(bson.D{{Key: "$inc", Value: bson.D{{Key: "rank", Value: 10}}}}),
+		mongo.NewUpdateOneModel().SetFilter(bson.D{{Key: "_id", Value: "d"}}).SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "rank", Value: 4}}}}).SetUpsert(true),
+		mongo.NewReplaceOneModel().SetFilter(bson.D{{Key: "_id", Value: "b"}}).SetReplacement(memoryMongoItem{Id: "b", Group: "replaced"}),
+		mongo.NewDeleteManyModel().SetFilter(bson.D{{Key: "rank", Value: bson.D{{Key: "$in", Value: bson.A{3, 4}}}}}),
+	})
+	if err != nil {
+		t.Fatalf("bulk write: %v", err)
+	}
+	if result.InsertedCount != 1 || result.MatchedCount != 2 || result.ModifiedCount != 2 || result.UpsertedCount != 1 || result.DeletedCount != 2 || result.UpsertedIDs[2] != "d" {
+		t.Fatalf("got %+v", result)
+	}
+
+	if ids := findMemoryMongoIds(t, db, nil, options.Find().SetSort(map[string]interface{}{"_id": 1})); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("got %v", ids)
+	}
+}
+
+func TestMemoryMongoWatchReportsCommittedChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db := NewMemoryMongoDatabase()
+
+	stream, err := db.Watch(ctx, "items", []interface{}{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace", "delete"}}}}}}},
+	})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer stream.Close(ctx)
+
+	seedMemoryMongo(t, db, memoryMongoItem{Id: "a", Rank: 1})
+	if _, err := db.UpdateOne(ctx, "items", map[string]interface{}{"_id": "a"}, map[string]interface{}{"$set": map[string]interface{}{"rank": 2}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	_ = db.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := db.InsertOne(ctx, "items", memoryMongoItem{Id: "rolled-back"})
+		if err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if _, err := db.DeleteMany(ctx, "items", nil); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	want := []string{"insert a 1", "update a 2", "delete a 0"}
+	for _, expected := range want {
+		if !stream.Next(ctx) {
+			t.Fatalf("stream stopped: %v", stream.Err())
+		}
+
+		var event struct {
+			OperationType string `bson:"operationType"`
+			DocumentKey   struct {
+				Id string `bson:"_id"`
+			} `bson:"documentKey"`
+			FullDocument memoryMongoItem `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		if got := fmt.Sprintf("%s %s %d", event.OperationType, event.DocumentKey.Id, event.FullDocument.Rank); got != expected {
+			t.Fatalf("got event %q, want %q", got, expected)
+		}
+	}
+
+	if err := stream.Close(ctx); err != nil || stream.Next(ctx) {
+		t.Fatalf("stream still open after close: %v", err)
+	}
+}
+
+func TestMemoryMongoRejectsUnsupportedOperations(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryMongoDatabase()
+	seedMemoryMongo(t, db, memoryMongoItem{Id: "a"})
+
+	if _, err := db.Aggregate(ctx, "items", []interface{}{bson.D{{Key: "$lookup", Value: bson.D{}}}}); !errors.Is(err, ErrMemoryMongoUnsupported) {
+		t.Fatalf("aggregate: got %v", err)
+	}
+	if _, err := db.Find(ctx, "items", map[string]interface{}{"$where": "true"}); !errors.Is(err, ErrMemoryMongoUnsupported) {
+		t.Fatalf("find: got %v", err)
+	}
+	if _, err := db.UpdateMany(ctx, "items", nil, map[string]interface{}{"$rename": map[string]interface{}{"rank": "position"}}); !errors.Is(err, ErrMemoryMongoUnsupported) {
+		t.Fatalf("update many: got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package library
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"strings"
+)
+
+const protobufSchemaFile = "schema.proto"
+
+var protobufSchemaTypes = map[string]string{
+	schemaTypeString:  "string",
+	schemaTypeBytes:   "bytes",
+	schemaTypeInt:     "int32",
+	schemaTypeLong:    "int64",
+	schemaTypeBoolean: "bool",
+}
+
+type (
+	protobufCodec struct{}
+
+	protobufDescriptor struct {
+		message        protoreflect.MessageDescriptor
+		messageIndexes []int
+	}
+)
+
+func (p protobufCodec) schemaType() string {
+	return SchemaTypeProtobuf
+}
+
+func (p protobufCodec) schema(record schemaRecord) (string, error) {
+	var schema strings.Builder
+	schema.WriteString("syntax = \"proto3\";\n")
+	schema.WriteString("package " + schemaNamespace + ";\n\n")
+	writeProtobufMessage(&schema, record, "")
+
+	return schema.String(), nil
+}
+
+func (p protobufCodec) parse(schema string, messageIndexes []int) (schemaDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{protobufSchemaFile: schema}),
+		}),
+	}
+
+	files, err := compiler.Compile(context.Background(), protobufSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse protobuf schema: %w", err)
+	}
+
+	if len(messageIndexes) == 0 {
+		return nil, errors.New("protobuf schema has no message")
+	}
+
+	messages := files[0].Messages()
+	var message protoreflect.MessageDescriptor
+	for _, index := range messageIndexes {
+		if index < 0 || index >= messages.Len() {
+			return nil, fmt.Errorf("message index %v not found in protobuf schema", messageIndexes)
+		}
+		message = messages.Get(index)
+		messages = message.Messages()
+	}
+
+	return &protobufDescriptor{message: message, messageIndexes: messageIndexes}, nil
+}
+
+func (p protobufCodec) readMessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, nil, errors.New("invalid message index")
+	}
+	data = data[n:]
+
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+
+	indexes := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		index, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, nil, errors.New("invalid message index")
+		}
+		data = data[n:]
+		indexes = append(indexes, int(index))
+	}
+
+	return indexes, data, nil
+}
+
+func (d *protobufDescriptor) encode(value map[string]interface{}) ([]byte, error) {
+	message := dynamicpb.NewMessage(d.message)
+	if err := setProtobufMessage(message, value); err != nil {
+		return nil, err
+	}
+
+	data := appendProtobufMessageIndexes(nil, d.messageIndexes)
+
+	return proto.MarshalOptions{}.MarshalAppend(data, message)
+}
+
+func (d *protobufDescriptor) decode(data []byte) (map[string]interface{}, error) {
+	message := dynamicpb.NewMessage(d.message)
+	if err := proto.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+
+	return protobufMessageValue(message), nil
+}
+
+func writeProtobufMessage(schema *strings.Builder, record schemaRecord, indent string) {
+	schema.WriteString(indent + "message " + record.Name + " {\n")
+
+	for _, field := range record.Fields {
+		fieldType := protobufSchemaTypes[field.Type]
+		if field.Record != nil {
+			fieldType = field.Record.Name
+		}
+
+		schema.WriteString(fmt.Sprintf("%s  %s %s = %d;\n", indent, fieldType, field.Name, field.Number))
+	}
+
+	for _, field := range record.Fields {
+		if field.Record != nil {
+			schema.WriteString("\n")
+			writeProtobufMessage(schema, *field.Record, indent+"  ")
+		}
+	}
+
+	schema.WriteString(indent + "}\n")
+}
+
+func appendProtobufMessageIndexes(data []byte, messageIndexes []int) []byte {
+	if len(messageIndexes) == 1 && messageIndexes[0] == 0 {
+		return binary.AppendVarint(data, 0)
+	}
+
+	data = binary.AppendVarint(data, int64(len(messageIndexes)))
+	for _, index := range messageIndexes {
+		data = binary.AppendVarint(data, int64(index))
+	}
+
+	return data
+}
+
+func setProtobufMessage(message protoreflect.Message, value map[string]interface{}) error {
+	fields := message.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		name := string(field.Name())
+
+		fieldValue, ok := value[name]
+		if !ok || fieldValue == nil {
+			continue
+		}
+
+		if field.IsList() || field.IsMap() {
+			return fmt.Errorf("unsupported repeated field %s", name)
+		}
+
+		var v protoreflect.Value
+		switch field.Kind() {
+		case protoreflect.StringKind:
+			s, err := schemaString(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfString(s)
+		case protoreflect.BytesKind:
+			b, err := schemaBytes(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfBytes(b)
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			n, err := schemaLong(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfInt32(int32(n))
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			n, err := schemaLong(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfInt64(n)
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+			n, err := schemaLong(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfUint32(uint32(n))
+		case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			n, err := schemaLong(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfUint64(uint64(n))
+		case protoreflect.BoolKind:
+			b, err := schemaBoolean(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfBool(b)
+		case protoreflect.MessageKind:
+			m, err := schemaMap(name, fieldValue)
+			if err != nil {
+				return err
+			}
+			nested := message.NewField(field).Message()
+			if err := setProtobufMessage(nested, m); err != nil {
+				return err
+			}
+			v = protoreflect.ValueOfMessage(nested)
+		default:
+			return fmt.Errorf("unsupported protobuf type %s for field %s", field.Kind(), name)
+		}
+
+		message.Set(field, v)
+	}
+
+	return nil
+}
+
+func protobufMessageValue(message protoreflect.Message) map[string]interface{} {
+	value := map[string]interface{}{}
+
+	fields := message.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Message() != nil && !field.IsList() && !field.IsMap() && !message.Has(field) {
+			continue
+		}
+		fieldValue := message.Get(field)
+
+		switch {
+		case field.IsList():
+			list := fieldValue.List()
+			items := make([]interface{}, 0, list.Len())
+			for j := 0; j < list.Len(); j++ {
+				items = append(items, protobufScalarValue(field, list.Get(j)))
+			}
+			value[string(field.Name())] = items
+		case field.IsMap():
+			entries := map[string]interface{}{}
+			fieldValue.Map().Range(func(key protoreflect.MapKey, entry protoreflect.Value) bool {
+				entries[key.String()] = protobufScalarValue(field.MapValue(), entry)
+				return true
+			})
+			value[string(field.Name())] = entries
+		default:
+			value[string(field.Name())] = protobufScalarValue(field, fieldValue)
+		}
+	}
+
+	return value
+}
+
+func protobufScalarValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return value.String()
+	case protoreflect.BytesKind:
+		return value.Bytes()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return value.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return value.Uint()
+	case protoreflect.BoolKind:
+		return value.Bool()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return value.Float()
+	case protoreflect.EnumKind:
+		return int64(value.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protobufMessageValue(value.Message())
+	default:
+		return nil
+	}
+}
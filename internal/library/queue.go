@@ -0,0 +1,299 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"github.com/IBM/sarama"
+	"github.com/redis/go-redis/v9"
+	"hash/fnv"
+	"salesforce-sse-worker/configs"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderRequestId     = "request-id"
+	HeaderCorrelationId = "correlation-id"
+	HeaderReplyTopic    = "reply-topic"
+
+	HeaderDlqReason            = "dlq-reason"
+	HeaderDlqOriginalTopic     = "dlq-original-topic"
+	HeaderDlqOriginalPartition = "dlq-original-partition"
+	HeaderDlqOriginalOffset    = "dlq-original-offset"
+)
+
+type (
+	Message struct {
+		Topic           string
+		Partition       int32
+		ManualPartition bool
+		Offset          int64
+		Key             []byte
+		Value           []byte
+		Headers         []MessageHeader
+		Timestamp       time.Time
+	}
+
+	MessageHeader struct {
+		Key   string
+		Value []byte
+	}
+
+	QueueProducer interface {
+		Produce(ctx context.Context, msg *Message) (partition int32, offset int64, err error)
+		Close() error
+	}
+
+	QueueHandler interface {
+		Setup(ctx context.Context, claims map[string][]int32) error
+		Cleanup(ctx context.Context, claims map[string][]int32) error
+		Handle(ctx context.Context, message *Message) error
+	}
+
+	QueueConsumer interface {
+		Consume(ctx context.Context)
+		Status() ConsumerStatus
+		Pause(partitions map[string][]int32)
+		Resume(partitions map[string][]int32)
+		Close() error
+	}
+
+	QueueAdmin interface {
+		PartitionCount(ctx context.Context, topic string) (int, error)
+		ConsumerGroupLag(ctx context.Context, group string, topic string) (int64, error)
+		ReadMessages(ctx context.Context, topic string, limit int) ([]*Message, error)
+		ResetOffsets(ctx context.Context, group string, topic string, timestamp time.Time) error
+		CommittedOffsets(ctx context.Context, group string, topic string) (map[int32]int64, error)
+		CommitOffsets(ctx context.Context, group string, topic string, offsets map[int32]int64) error
+	}
+
+	QueueReplyListener interface {
+		Enabled() bool
+		Topic() string
+		Register(correlationId string) <-chan *Message
+		Unregister(correlationId string)
+		Listen(ctx context.Context)
+		Close() error
+	}
+
+	QueueBackend interface {
+		NewProducer() (QueueProducer, error)
+		NewConsumer(handler QueueHandler) (QueueConsumer, error)
+		NewAdmin() (QueueAdmin, error)
+		NewReplyListener() (QueueReplyListener, error)
+	}
+
+	KafkaQueueBackendImpl struct {
+		queueConfig configs.QueueConfig
+		kafkaConfig configs.KafkaConfig
+		saramaCfg   *sarama.Config
+	}
+
+	MemoryQueueBackendImpl struct {
+		queueConfig configs.QueueConfig
+		broker      *MemoryBroker
+	}
+
+	RedisQueueBackendImpl struct {
+		queueConfig configs.QueueConfig
+		redisConfig configs.RedisConfig
+		client      *redis.Client
+	}
+
+	DisabledReplyListenerImpl struct {
+		*replyRegistry
+	}
+
+	replyRegistry struct {
+		mu      sync.Mutex
+		pending map[string]chan *Message
+	}
+)
+
+func NewKafkaQueueBackend(queueConfig configs.QueueConfig, kafkaConfig configs.KafkaConfig, saramaCfg *sarama.Config) QueueBackend {
+	return &KafkaQueueBackendImpl{queueConfig: queueConfig, kafkaConfig: kafkaConfig, saramaCfg: saramaCfg}
+}
+
+func (b *KafkaQueueBackendImpl) NewProducer() (QueueProducer, error) {
+	return NewKafkaProducer(b.kafkaConfig, b.saramaCfg)
+}
+
+func (b *KafkaQueueBackendImpl) NewConsumer(handler QueueHandler) (QueueConsumer, error) {
+	return NewKafkaConsumer(b.kafkaConfig, b.queueConfig, b.saramaCfg, handler)
+}
+
+func (b *KafkaQueueBackendImpl) NewAdmin() (QueueAdmin, error) {
+	return NewKafkaAdmin(b.kafkaConfig, b.saramaCfg)
+}
+
+func (b *KafkaQueueBackendImpl) NewReplyListener() (QueueReplyListener, error) {
+	return NewKafkaReplyListener(b.kafkaConfig, b.queueConfig, b.saramaCfg)
+}
+
+func NewMemoryQueueBackend(queueConfig configs.QueueConfig, broker *MemoryBroker) QueueBackend {
+	return &MemoryQueueBackendImpl{queueConfig: queueConfig, broker: broker}
+}
+
+func (b *MemoryQueueBackendImpl) NewProducer() (QueueProducer, error) {
+	return NewMemoryProducer(b.broker), nil
+}
+
+func (b *MemoryQueueBackendImpl) NewConsumer(handler QueueHandler) (QueueConsumer, error) {
+	return NewMemoryConsumer(b.queueConfig, b.broker, handler), nil
+}
+
+func (b *MemoryQueueBackendImpl) NewAdmin() (QueueAdmin, error) {
+	return NewMemoryAdmin(b.broker), nil
+}
+
+func (b *MemoryQueueBackendImpl) NewReplyListener() (QueueReplyListener, error) {
+	return NewMemoryReplyListener(b.queueConfig, b.broker), nil
+}
+
+func NewRedisQueueBackend(queueConfig configs.QueueConfig, redisConfig configs.RedisConfig, client *redis.Client) QueueBackend {
+	return &RedisQueueBackendImpl{queueConfig: queueConfig, redisConfig: redisConfig, client: client}
+}
+
+func (b *RedisQueueBackendImpl) NewProducer() (QueueProducer, error) {
+	return NewRedisProducer(b.redisConfig, b.client), nil
+}
+
+func (b *RedisQueueBackendImpl) NewConsumer(handler QueueHandler) (QueueConsumer, error) {
+	return NewRedisConsumer(b.redisConfig, b.queueConfig, b.client, handler)
+}
+
+func (b *RedisQueueBackendImpl) NewAdmin() (QueueAdmin, error) {
+	return NewRedisAdmin(b.redisConfig, b.client), nil
+}
+
+func (b *RedisQueueBackendImpl) NewReplyListener() (QueueReplyListener, error) {
+	return NewRedisReplyListener(b.redisConfig, b.queueConfig, b.client), nil
+}
+
+func NewQueueProducer(backend QueueBackend, lifecycle Lifecycle) (QueueProducer, error) {
+	producer, err := backend.NewProducer()
+	if err != nil {
+		return nil, err
+	}
+
+	lifecycle.Append(LifecycleHook{
+		Name:  "queue producer",
+		Order: LifecycleOrderQueue,
+		OnStop: func(ctx context.Context) error {
+			return producer.Close()
+		},
+	})
+
+	return producer, nil
+}
+
+func NewQueueConsumer(backend QueueBackend, handler QueueHandler, lifecycle Lifecycle) (QueueConsumer, error) {
+	consumer, err := backend.NewConsumer(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	lifecycle.AppendBackground("queue consumer", LifecycleOrderConsumer, func(ctx context.Context) error {
+		consumer.Consume(ctx)
+
+		err := consumer.Close()
+		if ctx.Err() == nil {
+			return errors.Join(errors.New("consumer stopped unexpectedly"), err)
+		}
+
+		return err
+	})
+
+	return consumer, nil
+}
+
+func NewQueueAdmin(backend QueueBackend) (QueueAdmin, error) {
+	return backend.NewAdmin()
+}
+
+func NewQueueReplyListener(backend QueueBackend, lifecycle Lifecycle) (QueueReplyListener, error) {
+	listener, err := backend.NewReplyListener()
+	if err != nil {
+		return nil, err
+	}
+
+	if listener.Enabled() {
+		lifecycle.AppendBackground("queue reply listener", LifecycleOrderQueue, func(ctx context.Context) error {
+			listener.Listen(ctx)
+
+			return listener.Close()
+		})
+	}
+
+	return listener, nil
+}
+
+func NewDisabledReplyListener() QueueReplyListener {
+	return &DisabledReplyListenerImpl{replyRegistry: newReplyRegistry()}
+}
+
+func (l *DisabledReplyListenerImpl) Enabled() bool {
+	return false
+}
+
+func (l *DisabledReplyListenerImpl) Topic() string {
+	return ""
+}
+
+func (l *DisabledReplyListenerImpl) Listen(ctx context.Context) {
+}
+
+func (l *DisabledReplyListenerImpl) Close() error {
+	return nil
+}
+
+func GetHeader(message *Message, key string) string {
+	for _, header := range message.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
+
+func hashPartition(key []byte, partitions int) int32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write(key)
+
+	return int32(hash.Sum32() % uint32(partitions))
+}
+
+func newReplyRegistry() *replyRegistry {
+	return &replyRegistry{pending: map[string]chan *Message{}}
+}
+
+func (r *replyRegistry) Register(correlationId string) <-chan *Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replies := make(chan *Message, 1)
+	r.pending[correlationId] = replies
+
+	return replies
+}
+
+func (r *replyRegistry) Unregister(correlationId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pending, correlationId)
+}
+
+func (r *replyRegistry) dispatch(message *Message) {
+	correlationId := GetHeader(message, HeaderCorrelationId)
+
+	r.mu.Lock()
+	replies, ok := r.pending[correlationId]
+	delete(r.pending, correlationId)
+	r.mu.Unlock()
+
+	if ok {
+		replies <- message
+	}
+}
@@ -0,0 +1,697 @@
+package library
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"math"
+	"os"
+	"salesforce-sse-worker/configs"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	redisFieldKey     = "key"
+	redisFieldValue   = "value"
+	redisFieldHeaders = "headers"
+
+	redisSequenceLimit = 1_000_000
+)
+
+var redisAcquireLease = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+elseif owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var redisReleaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type (
+	RedisProducerImpl struct {
+		cfg    configs.RedisConfig
+		client *redis.Client
+		next   atomic.Uint32
+	}
+
+	RedisConsumerImpl struct {
+		*consumerTracker
+		cfg     configs.RedisConfig
+		client  *redis.Client
+		topics  []string
+		group   string
+		owner   string
+		handler QueueHandler
+	}
+
+	RedisAdminImpl struct {
+		cfg    configs.RedisConfig
+		client *redis.Client
+	}
+
+	RedisReplyListenerImpl struct {
+		*replyRegistry
+		cfg     configs.RedisConfig
+		client  *redis.Client
+		topic   string
+		startId string
+	}
+
+	redisShardSession struct {
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+)
+
+func NewRedisProducer(cfg configs.RedisConfig, client *redis.Client) QueueProducer {
+	return &RedisProducerImpl{cfg: cfg, client: client}
+}
+
+func (p *RedisProducerImpl) Produce(ctx context.Context, msg *Message) (partition int32, offset int64, err error) {
+	if msg.ManualPartition {
+		if msg.Partition < 0 || int(msg.Partition) >= p.cfg.Shards {
+			return -1, -1, fmt.Errorf("shard %d out of range", msg.Partition)
+		}
+		partition = msg.Partition
+	} else if msg.Key != nil {
+		partition = hashPartition(msg.Key, p.cfg.Shards)
+	} else {
+		partition = int32(p.next.Add(1) % uint32(p.cfg.Shards))
+	}
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return -1, -1, fmt.Errorf("failed to encode headers: %w", err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: redisStream(msg.Topic, partition),
+		Values: map[string]interface{}{
+			redisFieldKey:     msg.Key,
+			redisFieldValue:   msg.Value,
+			redisFieldHeaders: headers,
+		},
+	}
+	if p.cfg.StreamMaxLen > 0 {
+		args.MaxLen = int64(p.cfg.StreamMaxLen)
+		args.Approx = true
+	}
+
+	id, err := p.client.XAdd(ctx, args).Result()
+	if err != nil {
+		return -1, -1, err
+	}
+
+	offset, err = redisOffset(id)
+	if err != nil {
+		return partition, -1, fmt.Errorf("entry %s was added but has no offset: %w", id, err)
+	}
+
+	return partition, offset, nil
+}
+
+func (p *RedisProducerImpl) Close() error {
+	return nil
+}
+
+func NewRedisConsumer(cfg configs.RedisConfig, queueConfig configs.QueueConfig, client *redis.Client, handler QueueHandler) (QueueConsumer, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisConsumerImpl{
+		consumerTracker: newConsumerTracker(),
+		cfg:             cfg,
+		client:          client,
+		topics:          queueConfig.Topics,
+		group:           queueConfig.GroupName,
+		owner:           hostname + "-" + redisRandomId(),
+		handler:         handler,
+	}, nil
+}
+
+func (c *RedisConsumerImpl) Pause(partitions map[string][]int32) {
+	c.setPaused(partitions, true)
+}
+
+func (c *RedisConsumerImpl) Resume(partitions map[string][]int32) {
+	c.setPaused(partitions, false)
+}
+
+func (c *RedisConsumerImpl) Close() error {
+	return nil
+}
+
+func (c *RedisConsumerImpl) Consume(ctx context.Context) {
+	ticker := time.NewTicker(getRedisDuration(c.cfg.LeaseTimeout) / 3)
+	defer ticker.Stop()
+
+	var owned []int32
+	sessions := map[int32]*redisShardSession{}
+	for {
+		shards := c.rebalance(ctx, owned)
+		if !slices.Equal(shards, owned) {
+			slog.InfoContext(ctx, "Redis shard assignment changed", slog.Any("previous", owned), slog.Any("current", shards))
+
+			released := redisShardsDiff(owned, shards)
+			c.stopShards(ctx, sessions, released)
+			c.release(ctx, released)
+			c.nextGeneration(c.claims(shards))
+			c.startShards(ctx, sessions, redisShardsDiff(shards, owned))
+			owned = shards
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Context cancelled, stopping consumer")
+
+			c.stopShards(ctx, sessions, owned)
+			c.leave(context.WithoutCancel(ctx), owned)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *RedisConsumerImpl) rebalance(ctx context.Context, owned []int32) []int32 {
+	now := time.Now()
+	ttl := getRedisDuration(c.cfg.LeaseTimeout)
+	membersKey := c.group + ":members"
+
+	if err := c.client.ZAdd(ctx, membersKey, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: c.owner}).Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to register Redis consumer", slog.Any("error", err))
+		return owned
+	}
+
+	if err := c.client.ZRemRangeByScore(ctx, membersKey, "-inf", strconv.FormatInt(now.UnixMilli(), 10)).Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to expire Redis consumers", slog.Any("error", err))
+	}
+
+	members, err := c.client.ZCard(ctx, membersKey).Result()
+	if err != nil || members == 0 {
+		slog.ErrorContext(ctx, "Failed to count Redis consumers", slog.Any("error", err))
+		return owned
+	}
+	target := (c.cfg.Shards + int(members) - 1) / int(members)
+
+	shards := []int32{}
+	for _, shard := range owned {
+		if len(shards) < target && c.acquire(ctx, shard, ttl) {
+			shards = append(shards, shard)
+		}
+	}
+
+	for shard := int32(0); shard < int32(c.cfg.Shards) && len(shards) < target; shard++ {
+		if !slices.Contains(shards, shard) && c.acquire(ctx, shard, ttl) {
+			shards = append(shards, shard)
+		}
+	}
+	slices.Sort(shards)
+
+	return shards
+}
+
+func (c *RedisConsumerImpl) acquire(ctx context.Context, shard int32, ttl time.Duration) bool {
+	acquired, err := redisAcquireLease.Run(ctx, c.client, []string{c.leaseKey(shard)}, c.owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to acquire Redis shard lease", slog.Int("shard", int(shard)), slog.Any("error", err))
+		return false
+	}
+
+	return acquired == 1
+}
+
+func (c *RedisConsumerImpl) release(ctx context.Context, shards []int32) {
+	for _, shard := range shards {
+		if err := redisReleaseLease.Run(ctx, c.client, []string{c.leaseKey(shard)}, c.owner).Err(); err != nil {
+			slog.ErrorContext(ctx, "Failed to release Redis shard lease", slog.Int("shard", int(shard)), slog.Any("error", err))
+		}
+	}
+}
+
+func (c *RedisConsumerImpl) leave(ctx context.Context, owned []int32) {
+	c.release(ctx, owned)
+
+	if err := c.client.ZRem(ctx, c.group+":members", c.owner).Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to unregister Redis consumer", slog.Any("error", err))
+	}
+}
+
+func (c *RedisConsumerImpl) startShards(ctx context.Context, sessions map[int32]*redisShardSession, shards []int32) {
+	for _, shard := range shards {
+		shardCtx, cancel := context.WithCancel(ctx)
+		session := &redisShardSession{cancel: cancel}
+		sessions[shard] = session
+
+		if err := c.handler.Setup(shardCtx, c.claims([]int32{shard})); err != nil {
+			slog.ErrorContext(ctx, "Failed to set up consumer", slog.Int("shard", int(shard)), slog.Any("error", err))
+		}
+
+		for _, topic := range c.topics {
+			session.wg.Add(1)
+
+			go func(topic string) {
+				defer session.wg.Done()
+				c.consumeShard(shardCtx, topic, shard)
+			}(topic)
+		}
+	}
+}
+
+func (c *RedisConsumerImpl) stopShards(ctx context.Context, sessions map[int32]*redisShardSession, shards []int32) {
+	if len(shards) == 0 {
+		return
+	}
+
+	for _, shard := range shards {
+		if session, ok := sessions[shard]; ok {
+			session.cancel()
+		}
+	}
+
+	for _, shard := range shards {
+		if session, ok := sessions[shard]; ok {
+			session.wg.Wait()
+			delete(sessions, shard)
+		}
+	}
+
+	if err := c.handler.Cleanup(context.WithoutCancel(ctx), c.claims(shards)); err != nil {
+		slog.ErrorContext(ctx, "Failed to clean up consumer", slog.Any("error", err))
+	}
+}
+
+func (c *RedisConsumerImpl) claims(shards []int32) map[string][]int32 {
+	claims := map[string][]int32{}
+	if len(shards) == 0 {
+		return claims
+	}
+
+	for _, topic := range c.topics {
+		claims[topic] = shards
+	}
+
+	return claims
+}
+
+func (c *RedisConsumerImpl) consumeShard(ctx context.Context, topic string, shard int32) {
+	stream := redisStream(topic, shard)
+	if err := createRedisGroup(ctx, c.client, stream, c.group, "0"); err != nil {
+		slog.ErrorContext(ctx, "Failed to create Redis consumer group", slog.String("stream", stream), slog.Any("error", err))
+		return
+	}
+
+	start := "0"
+	var reclaimedAt time.Time
+	for ctx.Err() == nil {
+		if !c.waitResumed(ctx, topic, shard) {
+			return
+		}
+
+		if time.Since(reclaimedAt) >= getRedisDuration(c.cfg.ClaimMinIdle) {
+			c.reclaim(ctx, topic, shard)
+			reclaimedAt = time.Now()
+		}
+
+		streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.owner,
+			Streams:  []string{stream, start},
+			Count:    int64(c.cfg.ReadBatchSize),
+			Block:    getRedisDuration(c.cfg.BlockTimeout),
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Error while reading Redis stream", slog.String("stream", stream), slog.Any("error", err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		if start == "0" && (len(streams) == 0 || len(streams[0].Messages) == 0) {
+			start = ">"
+			continue
+		}
+
+		for _, xStream := range streams {
+			for _, xMessage := range xStream.Messages {
+				c.handle(ctx, topic, shard, xMessage)
+			}
+		}
+	}
+}
+
+func (c *RedisConsumerImpl) reclaim(ctx context.Context, topic string, shard int32) {
+	stream := redisStream(topic, shard)
+	start := "0-0"
+	for ctx.Err() == nil {
+		messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    c.group,
+			Consumer: c.owner,
+			MinIdle:  getRedisDuration(c.cfg.ClaimMinIdle),
+			Start:    start,
+			Count:    int64(c.cfg.ReadBatchSize),
+		}).Result()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to reclaim pending Redis entries", slog.String("stream", stream), slog.Any("error", err))
+			return
+		}
+
+		for _, xMessage := range messages {
+			slog.InfoContext(ctx, "Reclaimed pending Redis entry", slog.String("stream", stream), slog.String("id", xMessage.ID))
+			c.handle(ctx, topic, shard, xMessage)
+		}
+
+		if next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+func (c *RedisConsumerImpl) handle(ctx context.Context, topic string, shard int32, xMessage redis.XMessage) {
+	stream := redisStream(topic, shard)
+
+	message, err := fromRedisMessage(topic, shard, xMessage)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to decode Redis entry", slog.String("stream", stream), slog.String("id", xMessage.ID), slog.Any("error", err))
+	} else {
+		c.begin(message)
+		if err := c.handler.Handle(ctx, message); err != nil {
+			slog.ErrorContext(ctx, "Failed to handle message", slog.Any("error", err))
+		}
+		c.end(message)
+	}
+
+	if err := c.client.XAck(context.WithoutCancel(ctx), stream, c.group, xMessage.ID).Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to acknowledge Redis entry", slog.String("stream", stream), slog.String("id", xMessage.ID), slog.Any("error", err))
+	}
+}
+
+func (c *RedisConsumerImpl) leaseKey(shard int32) string {
+	return fmt.Sprintf("%s:lease:%d", c.group, shard)
+}
+
+func NewRedisAdmin(cfg configs.RedisConfig, client *redis.Client) QueueAdmin {
+	return &RedisAdminImpl{cfg: cfg, client: client}
+}
+
+func (a *RedisAdminImpl) PartitionCount(ctx context.Context, topic string) (int, error) {
+	return a.cfg.Shards, nil
+}
+
+func (a *RedisAdminImpl) ConsumerGroupLag(ctx context.Context, group string, topic string) (int64, error) {
+	var lag int64
+	for shard := int32(0); shard < int32(a.cfg.Shards); shard++ {
+		groups, err := a.client.XInfoGroups(ctx, redisStream(topic, shard)).Result()
+		if err != nil {
+			if isRedisNoSuchKey(err) {
+				continue
+			}
+			return 0, fmt.Errorf("failed to read consumer groups for shard %d: %w", shard, err)
+		}
+
+		for _, info := range groups {
+			if info.Name != group {
+				continue
+			}
+
+			if info.Lag > 0 {
+				lag += info.Lag
+			}
+			lag += info.Pending
+		}
+	}
+
+	return lag, nil
+}
+
+func (a *RedisAdminImpl) ReadMessages(ctx context.Context, topic string, limit int) ([]*Message, error) {
+	messages := []*Message{}
+	for shard := int32(0); shard < int32(a.cfg.Shards); shard++ {
+		xMessages, err := a.client.XRevRangeN(ctx, redisStream(topic, shard), "+", "-", int64(limit)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read shard %d: %w", shard, err)
+		}
+
+		for i := len(xMessages) - 1; i >= 0; i-- {
+			message, err := fromRedisMessage(topic, shard, xMessages[i])
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	return messages, nil
+}
+
+func (a *RedisAdminImpl) ResetOffsets(ctx context.Context, group string, topic string, timestamp time.Time) error {
+	id := fmt.Sprintf("%d-%d", timestamp.UnixMilli()-1, uint64(math.MaxUint64))
+	for shard := int32(0); shard < int32(a.cfg.Shards); shard++ {
+		if err := setRedisGroupId(ctx, a.client, redisStream(topic, shard), group, id); err != nil {
+			return fmt.Errorf("failed to reset offset for shard %d: %w", shard, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *RedisAdminImpl) CommittedOffsets(ctx context.Context, group string, topic string) (map[int32]int64, error) {
+	offsets := map[int32]int64{}
+	for shard := int32(0); shard < int32(a.cfg.Shards); shard++ {
+		groups, err := a.client.XInfoGroups(ctx, redisStream(topic, shard)).Result()
+		if err != nil {
+			if isRedisNoSuchKey(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read consumer groups for shard %d: %w", shard, err)
+		}
+
+		for _, info := range groups {
+			if info.Name == group && info.LastDeliveredID != "0-0" {
+				offset, err := redisOffset(info.LastDeliveredID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read committed offset for shard %d: %w", shard, err)
+				}
+				offsets[shard] = offset + 1
+			}
+		}
+	}
+
+	return offsets, nil
+}
+
+func (a *RedisAdminImpl) CommitOffsets(ctx context.Context, group string, topic string, offsets map[int32]int64) error {
+	for shard, offset := range offsets {
+		if shard < 0 || int(shard) >= a.cfg.Shards {
+			return fmt.Errorf("shard %d out of range", shard)
+		}
+
+		if err := setRedisGroupId(ctx, a.client, redisStream(topic, shard), group, redisId(offset-1)); err != nil {
+			return fmt.Errorf("failed to commit offset for shard %d: %w", shard, err)
+		}
+	}
+
+	return nil
+}
+
+func NewRedisReplyListener(cfg configs.RedisConfig, queueConfig configs.QueueConfig, client *redis.Client) QueueReplyListener {
+	return &RedisReplyListenerImpl{
+		replyRegistry: newReplyRegistry(),
+		cfg:           cfg,
+		client:        client,
+		topic:         queueConfig.ReplyTopic,
+		startId:       fmt.Sprintf("%d-%d", time.Now().UnixMilli()-1, uint64(math.MaxUint64)),
+	}
+}
+
+func (l *RedisReplyListenerImpl) Enabled() bool {
+	return l.topic != ""
+}
+
+func (l *RedisReplyListenerImpl) Topic() string {
+	return l.topic
+}
+
+func (l *RedisReplyListenerImpl) Listen(ctx context.Context) {
+	if !l.Enabled() {
+		return
+	}
+
+	streams := make([]string, 2*l.cfg.Shards)
+	for shard := 0; shard < l.cfg.Shards; shard++ {
+		streams[shard] = redisStream(l.topic, int32(shard))
+		streams[l.cfg.Shards+shard] = l.startId
+	}
+
+	for ctx.Err() == nil {
+		results, err := l.client.XRead(ctx, &redis.XReadArgs{
+			Streams: streams,
+			Count:   int64(l.cfg.ReadBatchSize),
+			Block:   getRedisDuration(l.cfg.BlockTimeout),
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Error while consuming replies", slog.Any("error", err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, xStream := range results {
+			shard := redisShard(xStream.Stream)
+			for _, xMessage := range xStream.Messages {
+				message, err := fromRedisMessage(l.topic, shard, xMessage)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to decode reply", slog.String("stream", xStream.Stream), slog.String("id", xMessage.ID), slog.Any("error", err))
+				} else {
+					l.dispatch(message)
+				}
+
+				streams[l.cfg.Shards+int(shard)] = xMessage.ID
+			}
+		}
+	}
+}
+
+func (l *RedisReplyListenerImpl) Close() error {
+	return nil
+}
+
+func createRedisGroup(ctx context.Context, client *redis.Client, stream string, group string, start string) error {
+	if err := client.XGroupCreateMkStream(ctx, stream, group, start).Err(); err != nil && !redis.HasErrorPrefix(err, "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+func setRedisGroupId(ctx context.Context, client *redis.Client, stream string, group string, id string) error {
+	err := client.XGroupCreateMkStream(ctx, stream, group, id).Err()
+	if redis.HasErrorPrefix(err, "BUSYGROUP") {
+		err = client.XGroupSetID(ctx, stream, group, id).Err()
+	}
+
+	return err
+}
+
+func isRedisNoSuchKey(err error) bool {
+	return redis.HasErrorPrefix(err, "no such key")
+}
+
+func redisShardsDiff(shards []int32, other []int32) []int32 {
+	diff := []int32{}
+	for _, shard := range shards {
+		if !slices.Contains(other, shard) {
+			diff = append(diff, shard)
+		}
+	}
+
+	return diff
+}
+
+func fromRedisMessage(topic string, shard int32, xMessage redis.XMessage) (*Message, error) {
+	offset, err := redisOffset(xMessage.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		Topic:     topic,
+		Partition: shard,
+		Offset:    offset,
+		Timestamp: time.UnixMilli(offset / redisSequenceLimit),
+	}
+
+	if key, ok := xMessage.Values[redisFieldKey].(string); ok && key != "" {
+		message.Key = []byte(key)
+	}
+
+	if value, ok := xMessage.Values[redisFieldValue].(string); ok {
+		message.Value = []byte(value)
+	}
+
+	if headers, ok := xMessage.Values[redisFieldHeaders].(string); ok && headers != "" {
+		if err := json.Unmarshal([]byte(headers), &message.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode headers: %w", err)
+		}
+	}
+
+	return message, nil
+}
+
+func redisStream(topic string, shard int32) string {
+	return fmt.Sprintf("%s:%d", topic, shard)
+}
+
+func redisShard(stream string) int32 {
+	shard, _ := strconv.Atoi(stream[strings.LastIndex(stream, ":")+1:])
+
+	return int32(shard)
+}
+
+func redisOffset(id string) (int64, error) {
+	millis, sequence, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil || ms < 0 || ms > math.MaxInt64/redisSequenceLimit-1 {
+		return 0, fmt.Errorf("invalid entry id %q", id)
+	}
+
+	seq, err := strconv.ParseInt(sequence, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("invalid entry id %q", id)
+	}
+	if seq >= redisSequenceLimit {
+		return 0, fmt.Errorf("entry id %q has a sequence of %d or more and cannot be mapped to an offset", id, redisSequenceLimit)
+	}
+
+	return ms*redisSequenceLimit + seq, nil
+}
+
+func redisId(offset int64) string {
+	if offset < 0 {
+		return "0-0"
+	}
+
+	return fmt.Sprintf("%d-%d", offset/redisSequenceLimit, offset%redisSequenceLimit)
+}
+
+func redisRandomId() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+func getRedisDuration(millisecond int) time.Duration {
+	return time.Duration(millisecond) * time.Millisecond
+}
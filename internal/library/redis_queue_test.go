@@ -0,0 +1,344 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"salesforce-sse-worker/configs"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestRedis(t *testing.T) (configs.RedisConfig, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return configs.RedisConfig{
+		Shards:        4,
+		BlockTimeout:  50,
+		ClaimMinIdle:  60000,
+		LeaseTimeout:  300,
+		ReadBatchSize: 10,
+	}, client
+}
+
+func startRedisConsumer(t *testing.T, cfg configs.RedisConfig, client *redis.Client, handler QueueHandler) (QueueConsumer, context.CancelFunc) {
+	t.Helper()
+
+	consumer, err := NewRedisConsumer(cfg, configs.QueueConfig{Topics: []string{"commands"}, GroupName: "worker"}, client, handler)
+	if err != nil {
+		t.Fatalf("consumer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Consume(ctx)
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+
+	return consumer, stop
+}
+
+func waitForShards(t *testing.T, consumer QueueConsumer, want int) []int32 {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var shards []int32
+		for _, partition := range consumer.Status().Partitions {
+			shards = append(shards, partition.Partition)
+		}
+
+		if len(shards) == want {
+			return shards
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got shards %v, want %d", shards, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedisQueueDeliversMessages(t *testing.T) {
+	ctx := context.Background()
+	cfg, client := newTestRedis(t)
+	producer := NewRedisProducer(cfg, client)
+
+	for i := 0; i < 20; i++ {
+		if _, _, err := producer.Produce(ctx, &Message{Topic: "commands", Key: []byte(fmt.Sprintf("conversation-%d", i%5)), Value: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatalf("produce: %v", err)
+		}
+	}
+
+	handler := newRecordingQueueHandler()
+	consumer, _ := startRedisConsumer(t, cfg, client, handler)
+	waitForShards(t, consumer, 4)
+
+	last := map[string]int{}
+	for _, message := range handler.wait(t, 20) {
+		var value int
+		if _, err := fmt.Sscan(string(message.Value), &value); err != nil {
+			t.Fatalf("decode value: %v", err)
+		}
+
+		key := string(message.Key)
+		if previous, ok := last[key]; ok && value < previous {
+			t.Fatalf("key %s: got %d after %d", key, value, previous)
+		}
+		last[key] = value
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for shard := int32(0); shard < int32(cfg.Shards); {
+		stream := redisStream("commands", shard)
+
+		groups, err := client.XInfoGroups(ctx, stream).Result()
+		if err != nil {
+			t.Fatalf("groups: %v", err)
+		}
+		tail, err := client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+		if err != nil {
+			t.Fatalf("tail: %v", err)
+		}
+
+		if len(groups) == 1 && groups[0].Pending == 0 && (len(tail) == 0 || groups[0].LastDeliveredID == tail[0].ID) {
+			shard++
+			continue
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("shard %d not acknowledged: %+v", shard, groups)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedisQueueRebalancesOnlyChangedShards(t *testing.T) {
+	cfg, client := newTestRedis(t)
+
+	first := newRecordingQueueHandler()
+	firstConsumer, _ := startRedisConsumer(t, cfg, client, first)
+	waitForShards(t, firstConsumer, 4)
+
+	second := newRecordingQueueHandler()
+	secondConsumer, stopSecond := startRedisConsumer(t, cfg, client, second)
+
+	kept := waitForShards(t, firstConsumer, 2)
+	taken := waitForShards(t, secondConsumer, 2)
+	for _, shard := range taken {
+		if slices.Contains(kept, shard) {
+			t.Fatalf("shard %d owned by both consumers: %v and %v", shard, kept, taken)
+		}
+	}
+
+	first.mu.Lock()
+	setups, cleanups := len(first.setups), slices.Clone(first.cleanups)
+	first.mu.Unlock()
+
+	if setups != 4 {
+		t.Fatalf("got %d setups, want one per initially owned shard", setups)
+	}
+	if len(cleanups) != 1 || !slices.Equal(cleanups[0]["commands"], taken) {
+		t.Fatalf("got cleanups %v, want only the released shards %v", cleanups, taken)
+	}
+
+	stopSecond()
+	waitForShards(t, firstConsumer, 4)
+
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	if len(first.setups) != 6 || len(first.cleanups) != 1 {
+		t.Fatalf("got setups %v and cleanups %v after taking back the released shards", first.setups, first.cleanups)
+	}
+}
+
+func TestRedisQueueReclaimsPendingEntries(t *testing.T) {
+	ctx := context.Background()
+	cfg, client := newTestRedis(t)
+	cfg.Shards = 1
+	cfg.ClaimMinIdle = 10
+
+	if err := createRedisGroup(ctx, client, redisStream("commands", 0), "worker", "0"); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if err := createRedisGroup(ctx, client, redisStream("commands", 0), "worker", "0"); err != nil {
+		t.Fatalf("create existing group: %v", err)
+	}
+
+	if _, _, err := NewRedisProducer(cfg, client).Produce(ctx, &Message{Topic: "commands", Key: []byte("key"), Value: []byte("orphaned")}); err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "worker",
+		Consumer: "crashed",
+		Streams:  []string{redisStream("commands", 0), ">"},
+	}).Err(); err != nil {
+		t.Fatalf("read as crashed consumer: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	handler := newRecordingQueueHandler()
+	startRedisConsumer(t, cfg, client, handler)
+
+	if messages := handler.wait(t, 1); string(messages[0].Value) != "orphaned" {
+		t.Fatalf("got %s", messages[0].Value)
+	}
+}
+
+func TestRedisAdminLagIgnoresMissingStreams(t *testing.T) {
+	cfg, client := newTestRedis(t)
+
+	lag, err := NewRedisAdmin(cfg, client).ConsumerGroupLag(context.Background(), "worker", "missing")
+	if err != nil || lag != 0 {
+		t.Fatalf("got lag %d and error %v", lag, err)
+	}
+}
+
+func TestRedisReplyListenerReceivesRepliesSentBeforeListening(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg, client := newTestRedis(t)
+	listener := NewRedisReplyListener(cfg, configs.QueueConfig{GroupName: "server", ReplyTopic: "replies"}, client)
+	replies := listener.Register("correlation")
+	defer listener.Unregister("correlation")
+
+	if _, _, err := NewRedisProducer(cfg, client).Produce(ctx, &Message{
+		Topic:   "replies",
+		Key:     []byte("correlation"),
+		Value:   []byte("reply"),
+		Headers: []MessageHeader{{Key: HeaderCorrelationId, Value: []byte("correlation")}},
+	}); err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+
+	go listener.Listen(ctx)
+
+	select {
+	case reply := <-replies:
+		if string(reply.Value) != "reply" {
+			t.Fatalf("got reply %s", reply.Value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reply was not dispatched")
+	}
+
+	groups, err := client.XInfoGroups(ctx, redisStream("replies", hashPartition([]byte("correlation"), cfg.Shards))).Result()
+	if err != nil {
+		t.Fatalf("groups: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("got reply groups %v, want none", groups)
+	}
+}
+
+func TestRedisAdminCommitsOffsets(t *testing.T) {
+	ctx := context.Background()
+	cfg, client := newTestRedis(t)
+	admin := NewRedisAdmin(cfg, client)
+
+	producer := NewRedisProducer(cfg, client)
+	var offsets []int64
+	for i := 0; i < 3; i++ {
+		_, offset, err := producer.Produce(ctx, &Message{Topic: "dlq", Partition: 1, ManualPartition: true, Value: []byte(fmt.Sprint(i))})
+		if err != nil {
+			t.Fatalf("produce: %v", err)
+		}
+		offsets = append(offsets, offset)
+	}
+
+	committed, err := admin.CommittedOffsets(ctx, "replay", "dlq")
+	if err != nil || len(committed) != 0 {
+		t.Fatalf("got committed offsets %v and error %v before any commit", committed, err)
+	}
+
+	if err := admin.CommitOffsets(ctx, "replay", "dlq", map[int32]int64{1: offsets[1] + 1}); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	committed, err = admin.CommittedOffsets(ctx, "replay", "dlq")
+	if err != nil {
+		t.Fatalf("committed: %v", err)
+	}
+	if len(committed) != 1 || committed[1] != offsets[1]+1 {
+		t.Fatalf("got committed offsets %v, want shard 1 at %d", committed, offsets[1]+1)
+	}
+
+	messages, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "replay", Consumer: "test", Streams: []string{redisStream("dlq", 1), ">"}}).Result()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(messages[0].Messages) != 1 {
+		t.Fatalf("got %v, want only the entry after the committed offset", messages)
+	}
+	if offset, err := redisOffset(messages[0].Messages[0].ID); err != nil || offset != offsets[2] {
+		t.Fatalf("got offset %d and error %v, want %d", offset, err, offsets[2])
+	}
+}
+
+func TestRedisOffsetRejectsUnrepresentableIds(t *testing.T) {
+	offset, err := redisOffset("1700000000000-999999")
+	if err != nil || redisId(offset) != "1700000000000-999999" {
+		t.Fatalf("got offset %d and error %v", offset, err)
+	}
+
+	for _, id := range []string{"1700000000000-1000000", "1700000000000", "x-1", "-1-0"} {
+		if _, err := redisOffset(id); err == nil {
+			t.Fatalf("accepted id %q", id)
+		}
+	}
+}
+
+type leaseRecordingQueueHandler struct {
+	*recordingQueueHandler
+	client *redis.Client
+	leases []string
+}
+
+func (h *leaseRecordingQueueHandler) Cleanup(ctx context.Context, claims map[string][]int32) error {
+	for _, shard := range claims["commands"] {
+		lease, _ := h.client.Get(ctx, fmt.Sprintf("worker:lease:%d", shard)).Result()
+		h.mu.Lock()
+		h.leases = append(h.leases, lease)
+		h.mu.Unlock()
+	}
+
+	return h.recordingQueueHandler.Cleanup(ctx, claims)
+}
+
+func TestRedisQueueReleasesLeasesAfterDrainingShards(t *testing.T) {
+	cfg, client := newTestRedis(t)
+
+	first := &leaseRecordingQueueHandler{recordingQueueHandler: newRecordingQueueHandler(), client: client}
+	firstConsumer, _ := startRedisConsumer(t, cfg, client, first)
+	waitForShards(t, firstConsumer, 4)
+
+	secondConsumer, _ := startRedisConsumer(t, cfg, client, newRecordingQueueHandler())
+	waitForShards(t, firstConsumer, 2)
+	waitForShards(t, secondConsumer, 2)
+
+	first.mu.Lock()
+	defer first.mu.Unlock()
+
+	owner := firstConsumer.(*RedisConsumerImpl).owner
+	if len(first.leases) != 2 {
+		t.Fatalf("got leases %v, want one per released shard", first.leases)
+	}
+	for _, lease := range first.leases {
+		if lease != owner {
+			t.Fatalf("got lease owner %q during cleanup, want %q", lease, owner)
+		}
+	}
+}
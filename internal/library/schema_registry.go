@@ -0,0 +1,178 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/request"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+
+	memorySchemaRegistryUrl = "mem://"
+	schemaRegistryMediaType = "application/vnd.schemaregistry.v1+json"
+)
+
+type (
+	SchemaRegistry interface {
+		Register(ctx context.Context, subject string, schemaType string, schema string) (int, error)
+		Schema(ctx context.Context, id int) (string, string, error)
+	}
+
+	SchemaRegistryImpl struct {
+		cfg        configs.SerializerConfig
+		httpClient HTTPClient
+	}
+
+	MemorySchemaRegistryImpl struct {
+		mu      sync.Mutex
+		ids     map[string]int
+		schemas []memorySchema
+	}
+
+	memorySchema struct {
+		schemaType string
+		schema     string
+	}
+)
+
+func NewSchemaRegistry(cfg configs.SerializerConfig, httpClient HTTPClient) SchemaRegistry {
+	if cfg.SchemaRegistryUrl == memorySchemaRegistryUrl {
+		return NewMemorySchemaRegistry()
+	}
+
+	return &SchemaRegistryImpl{cfg: cfg, httpClient: httpClient}
+}
+
+func NewMemorySchemaRegistry() SchemaRegistry {
+	return &MemorySchemaRegistryImpl{ids: map[string]int{}}
+}
+
+func (s *SchemaRegistryImpl) Register(ctx context.Context, subject string, schemaType string, schema string) (int, error) {
+	payload, err := json.Marshal(map[string]string{
+		"schema":     schema,
+		"schemaType": schemaType,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.httpClient.Post(ctx, request.HTTPRequest{
+		Path:    s.url("/subjects/" + url.PathEscape(subject) + "/versions"),
+		Headers: s.headers(),
+		Body:    bytes.NewReader(payload),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to register schema for subject %s: %w", subject, err)
+	}
+
+	var result struct {
+		Id int `json:"id"`
+	}
+	if err := readSchemaRegistryResponse(resp, &result); err != nil {
+		return 0, fmt.Errorf("failed to register schema for subject %s: %w", subject, err)
+	}
+
+	return result.Id, nil
+}
+
+func (s *SchemaRegistryImpl) Schema(ctx context.Context, id int) (string, string, error) {
+	resp, err := s.httpClient.Get(ctx, request.HTTPRequest{
+		Path:    s.url("/schemas/ids/" + strconv.Itoa(id)),
+		Headers: s.headers(),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+
+	var result struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := readSchemaRegistryResponse(resp, &result); err != nil {
+		return "", "", fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+
+	if result.SchemaType == "" {
+		result.SchemaType = SchemaTypeAvro
+	}
+
+	return result.SchemaType, result.Schema, nil
+}
+
+func (s *SchemaRegistryImpl) url(path string) string {
+	return strings.TrimRight(s.cfg.SchemaRegistryUrl, "/") + path
+}
+
+func (s *SchemaRegistryImpl) headers() map[string]string {
+	headers := map[string]string{
+		"Accept":       schemaRegistryMediaType,
+		"Content-Type": schemaRegistryMediaType,
+	}
+
+	if s.cfg.SchemaRegistryUsername != "" {
+		credentials := s.cfg.SchemaRegistryUsername + ":" + s.cfg.SchemaRegistryPassword
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	return headers
+}
+
+func readSchemaRegistryResponse(resp *http.Response, result interface{}) error {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("schema registry responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode schema registry response: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MemorySchemaRegistryImpl) Register(ctx context.Context, subject string, schemaType string, schema string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := subject + "/" + schemaType + "/" + schema
+	if id, ok := m.ids[key]; ok {
+		return id, nil
+	}
+
+	m.schemas = append(m.schemas, memorySchema{schemaType: schemaType, schema: schema})
+	id := len(m.schemas)
+	m.ids[key] = id
+
+	return id, nil
+}
+
+func (m *MemorySchemaRegistryImpl) Schema(ctx context.Context, id int) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id < 1 || id > len(m.schemas) {
+		return "", "", fmt.Errorf("schema %d not found", id)
+	}
+
+	schema := m.schemas[id-1]
+
+	return schema.schemaType, schema.schema, nil
+}
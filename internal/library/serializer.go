@@ -0,0 +1,229 @@
+package library
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/request"
+	"sync"
+)
+
+const (
+	SerializerFormatJSON     = "json"
+	SerializerFormatAvro     = "avro"
+	SerializerFormatProtobuf = "protobuf"
+
+	wireFormatMagicByte  = 0
+	wireFormatHeaderSize = 5
+)
+
+var ErrMalformedPayload = errors.New("malformed payload")
+
+type (
+	Serializer interface {
+		Serialize(ctx context.Context, topic string, envelope request.Envelope) ([]byte, error)
+		Deserialize(ctx context.Context, topic string, data []byte) (request.Envelope, error)
+	}
+
+	JSONSerializerImpl struct{}
+
+	schemaCodec interface {
+		schemaType() string
+		schema(record schemaRecord) (string, error)
+		parse(schema string, messageIndexes []int) (schemaDescriptor, error)
+		readMessageIndexes(data []byte) ([]int, []byte, error)
+	}
+
+	schemaDescriptor interface {
+		encode(value map[string]interface{}) ([]byte, error)
+		decode(data []byte) (map[string]interface{}, error)
+	}
+
+	commandSchema struct {
+		name       string
+		schema     string
+		descriptor schemaDescriptor
+	}
+
+	SchemaSerializerImpl struct {
+		mu             sync.Mutex
+		schemaIds      map[string]int
+		writerSchemas  map[string]schemaDescriptor
+		commandSchemas map[string]commandSchema
+		codec          schemaCodec
+		schemaRegistry SchemaRegistry
+		fallback       Serializer
+	}
+)
+
+func NewSerializer(cfg configs.SerializerConfig, schemaRegistry SchemaRegistry) (Serializer, error) {
+	switch cfg.Format {
+	case "", SerializerFormatJSON:
+		return &JSONSerializerImpl{}, nil
+	case SerializerFormatAvro:
+		return newSchemaSerializer(avroCodec{}, schemaRegistry)
+	case SerializerFormatProtobuf:
+		return newSchemaSerializer(protobufCodec{}, schemaRegistry)
+	default:
+		return nil, fmt.Errorf("unsupported serializer format %q", cfg.Format)
+	}
+}
+
+func (j *JSONSerializerImpl) Serialize(ctx context.Context, topic string, envelope request.Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (j *JSONSerializerImpl) Deserialize(ctx context.Context, topic string, data []byte) (request.Envelope, error) {
+	var envelope request.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return envelope, fmt.Errorf("%w: %w", ErrMalformedPayload, err)
+	}
+
+	return envelope, nil
+}
+
+func newSchemaSerializer(codec schemaCodec, schemaRegistry SchemaRegistry) (Serializer, error) {
+	records, err := newCommandSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	commandSchemas := map[string]commandSchema{}
+	for commandType, record := range records {
+		schema, err := codec.schema(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s schema: %w", commandType, err)
+		}
+
+		descriptor, err := codec.parse(schema, []int{0})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s schema: %w", commandType, err)
+		}
+
+		commandSchemas[commandType] = commandSchema{name: record.Name, schema: schema, descriptor: descriptor}
+	}
+
+	return &SchemaSerializerImpl{
+		schemaIds:      map[string]int{},
+		writerSchemas:  map[string]schemaDescriptor{},
+		commandSchemas: commandSchemas,
+		codec:          codec,
+		schemaRegistry: schemaRegistry,
+		fallback:       &JSONSerializerImpl{},
+	}, nil
+}
+
+func (s *SchemaSerializerImpl) Serialize(ctx context.Context, topic string, envelope request.Envelope) ([]byte, error) {
+	command, ok := s.commandSchemas[envelope.Type]
+	if !ok {
+		return nil, fmt.Errorf("no schema for command %q", envelope.Type)
+	}
+
+	value, err := envelopeToValue(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaId, err := s.schemaId(ctx, topic, command)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := command.descriptor.encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", envelope.Type, err)
+	}
+
+	header := make([]byte, wireFormatHeaderSize)
+	header[0] = wireFormatMagicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(schemaId))
+
+	return append(header, body...), nil
+}
+
+func (s *SchemaSerializerImpl) Deserialize(ctx context.Context, topic string, data []byte) (request.Envelope, error) {
+	if len(data) == 0 || data[0] != wireFormatMagicByte {
+		return s.fallback.Deserialize(ctx, topic, data)
+	}
+
+	if len(data) < wireFormatHeaderSize {
+		return request.Envelope{}, fmt.Errorf("%w: wire format header too short", ErrMalformedPayload)
+	}
+
+	schemaId := int(binary.BigEndian.Uint32(data[1:wireFormatHeaderSize]))
+
+	messageIndexes, body, err := s.codec.readMessageIndexes(data[wireFormatHeaderSize:])
+	if err != nil {
+		return request.Envelope{}, fmt.Errorf("%w: %w", ErrMalformedPayload, err)
+	}
+
+	descriptor, err := s.writerSchema(ctx, schemaId, messageIndexes)
+	if err != nil {
+		return request.Envelope{}, err
+	}
+
+	value, err := descriptor.decode(body)
+	if err != nil {
+		return request.Envelope{}, fmt.Errorf("%w: %w", ErrMalformedPayload, err)
+	}
+
+	return envelopeFromValue(value)
+}
+
+func (s *SchemaSerializerImpl) schemaId(ctx context.Context, topic string, command commandSchema) (int, error) {
+	subject := topic + "-" + schemaNamespace + "." + command.name
+
+	s.mu.Lock()
+	schemaId, ok := s.schemaIds[subject]
+	s.mu.Unlock()
+
+	if ok {
+		return schemaId, nil
+	}
+
+	schemaId, err := s.schemaRegistry.Register(ctx, subject, s.codec.schemaType(), command.schema)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.schemaIds[subject] = schemaId
+	s.mu.Unlock()
+
+	return schemaId, nil
+}
+
+func (s *SchemaSerializerImpl) writerSchema(ctx context.Context, schemaId int, messageIndexes []int) (schemaDescriptor, error) {
+	key := fmt.Sprint(schemaId, messageIndexes)
+
+	s.mu.Lock()
+	descriptor, ok := s.writerSchemas[key]
+	s.mu.Unlock()
+
+	if ok {
+		return descriptor, nil
+	}
+
+	schemaType, schema, err := s.schemaRegistry.Schema(ctx, schemaId)
+	if err != nil {
+		return nil, err
+	}
+
+	if schemaType != s.codec.schemaType() {
+		return nil, fmt.Errorf("%w: schema %d is %s, expected %s", ErrMalformedPayload, schemaId, schemaType, s.codec.schemaType())
+	}
+
+	descriptor, err = s.codec.parse(schema, messageIndexes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema %d: %w", ErrMalformedPayload, schemaId, err)
+	}
+
+	s.mu.Lock()
+	s.writerSchemas[key] = descriptor
+	s.mu.Unlock()
+
+	return descriptor, nil
+}
@@ -0,0 +1,405 @@
+package library
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/request"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubSchemaRegistry struct {
+	mu       sync.Mutex
+	subjects map[string]int
+	schemas  []map[string]string
+}
+
+func newStubSchemaRegistry(t *testing.T) (*stubSchemaRegistry, SchemaRegistry) {
+	t.Helper()
+
+	stub := &stubSchemaRegistry{subjects: map[string]int{}}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	return stub, NewSchemaRegistry(configs.SerializerConfig{SchemaRegistryUrl: server.URL}, NewHTTPClient())
+}
+
+func (s *stubSchemaRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/subjects/"):
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		subject := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/subjects/"), "/versions")
+		if body["schemaType"] == SchemaTypeAvro {
+			delete(body, "schemaType")
+		}
+		s.schemas = append(s.schemas, body)
+		s.subjects[subject] = len(s.schemas)
+
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": len(s.schemas)})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/schemas/ids/"):
+		id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/schemas/ids/"))
+		if err != nil || id < 1 || id > len(s.schemas) {
+			http.Error(w, `{"error_code":40403,"message":"Schema not found"}`, http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(s.schemas[id-1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *stubSchemaRegistry) register(t *testing.T, subject string, schemaType string, schema string) int {
+	t.Helper()
+
+	body := strings.NewReader(`{"schemaType":` + strconv.Quote(schemaType) + `,"schema":` + strconv.Quote(schema) + `}`)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/subjects/"+subject+"/versions", body))
+
+	var result struct {
+		Id int `json:"id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	return result.Id
+}
+
+func TestSchemaSerializerRoundTrip(t *testing.T) {
+	commands := map[string]interface{}{
+		request.CommandCreateConversation: request.CreateConversationRequest{
+			ConversationId:  "conversation",
+			EsDeveloperName: "developer",
+			Language:        "en",
+			RoutingAttributes: request.CreateConversationRoutingAttributes{
+				CaseId:        "case",
+				AccountId:     "account",
+				CustomerName:  "name",
+				CustomerPhone: "phone",
+				CustomerEmail: "email",
+				Origin:        "origin",
+				SourceType:    "source",
+			},
+			CallbackUrl: "https://example.com/callback",
+		},
+		request.CommandSendMessage:       request.SendMessageRequest{ConversationId: "conversation", EsDeveloperName: "developer", MessageId: "message", Text: "hello"},
+		request.CommandCloseConversation: request.CloseConversationRequest{ConversationId: "conversation", EsDeveloperName: "developer"},
+		request.CommandTyping:            request.TypingRequest{ConversationId: "conversation", EntryId: "entry", Started: true},
+		request.CommandAttachment:        request.AttachmentRequest{ConversationId: "conversation", EsDeveloperName: "developer", MessageId: "message", FileName: "file.txt", ContentType: "text/plain", Data: []byte{0, 1, 2, 255}},
+		request.CommandResubscribe:       request.ResubscribeRequest{Partition: 7},
+	}
+
+	for _, format := range []string{SerializerFormatAvro, SerializerFormatProtobuf} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			stub, schemaRegistry := newStubSchemaRegistry(t)
+
+			producer, err := NewSerializer(configs.SerializerConfig{Format: format}, schemaRegistry)
+			if err != nil {
+				t.Fatalf("producer serializer: %v", err)
+			}
+
+			consumer, err := NewSerializer(configs.SerializerConfig{Format: format}, schemaRegistry)
+			if err != nil {
+				t.Fatalf("consumer serializer: %v", err)
+			}
+
+			for commandType, payload := range commands {
+				encodedPayload, err := json.Marshal(payload)
+				if err != nil {
+					t.Fatalf("%s: marshal payload: %v", commandType, err)
+				}
+
+				envelope := request.Envelope{
+					Type:      commandType,
+					Version:   request.EnvelopeVersion,
+					Id:        "request-" + commandType,
+					Timestamp: time.UnixMilli(1700000000123),
+					Payload:   encodedPayload,
+				}
+
+				data, err := producer.Serialize(ctx, "commands", envelope)
+				if err != nil {
+					t.Fatalf("%s: serialize: %v", commandType, err)
+				}
+
+				decoded, err := consumer.Deserialize(ctx, "commands", data)
+				if err != nil {
+					t.Fatalf("%s: deserialize: %v", commandType, err)
+				}
+
+				if decoded.Type != envelope.Type || decoded.Version != envelope.Version || decoded.Id != envelope.Id || !decoded.Timestamp.Equal(envelope.Timestamp) {
+					t.Fatalf("%s: got envelope %+v, want %+v", commandType, decoded, envelope)
+				}
+
+				got := reflect.New(reflect.TypeOf(payload))
+				if err := json.Unmarshal(decoded.Payload, got.Interface()); err != nil {
+					t.Fatalf("%s: decode payload: %v", commandType, err)
+				}
+				if !reflect.DeepEqual(got.Elem().Interface(), payload) {
+					t.Fatalf("%s: got payload %+v, want %+v", commandType, got.Elem().Interface(), payload)
+				}
+			}
+
+			if len(stub.subjects) != len(commands) {
+				t.Fatalf("got subjects %v, want one per command", stub.subjects)
+			}
+
+			schema := stub.schemas[stub.subjects["commands-salesforce.sse.worker.ConversationCreate"]-1]["schema"]
+			if !strings.Contains(schema, "customerEmail") {
+				t.Fatalf("create conversation schema does not describe the payload: %s", schema)
+			}
+		})
+	}
+}
+
+func TestSchemaSerializerResolvesWriterSchema(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		format     string
+		schemaType string
+		schema     string
+		codec      schemaCodec
+	}{
+		{
+			format:     SerializerFormatAvro,
+			schemaType: SchemaTypeAvro,
+			schema: `{"type":"record","name":"ConversationTyping","namespace":"salesforce.sse.worker","fields":[` +
+				`{"name":"id","type":"string"},` +
+				`{"name":"type","type":"string"},` +
+				`{"name":"trace","type":"string"},` +
+				`{"name":"payload","type":{"type":"record","name":"TypingRequest","fields":[` +
+				`{"name":"started","type":"boolean"},` +
+				`{"name":"entryId","type":"string"},` +
+				`{"name":"conversationId","type":"string"}]}},` +
+				`{"name":"version","type":"int"},` +
+				`{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-millis"}}]}`,
+			codec: avroCodec{},
+		},
+		{
+			format:     SerializerFormatProtobuf,
+			schemaType: SchemaTypeProtobuf,
+			schema: `syntax = "proto3";
+package salesforce.sse.worker;
+
+// writer schema with an extra field and a different declaration order
+message ConversationTyping {
+  TypingRequest payload = 5;
+  string trace = 9 [deprecated = true];
+  string type = 1;
+  int32 version = 2;
+  string id = 3;
+  int64 timestamp = 4;
+
+  message TypingRequest {
+    bool started = 3;
+    string conversationId = 1;
+    string entryId = 2;
+  }
+}
+`,
+			codec: protobufCodec{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			stub, schemaRegistry := newStubSchemaRegistry(t)
+			schemaId := stub.register(t, "commands-salesforce.sse.worker.ConversationTyping", tt.schemaType, tt.schema)
+
+			descriptor, err := tt.codec.parse(tt.schema, []int{0})
+			if err != nil {
+				t.Fatalf("parse writer schema: %v", err)
+			}
+
+			body, err := descriptor.encode(map[string]interface{}{
+				"id":        "request",
+				"type":      request.CommandTyping,
+				"trace":     "trace",
+				"version":   int64(request.EnvelopeVersion),
+				"timestamp": int64(1700000000123),
+				"payload": map[string]interface{}{
+					"started":        true,
+					"entryId":        "entry",
+					"conversationId": "conversation",
+				},
+			})
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			data := make([]byte, wireFormatHeaderSize)
+			binary.BigEndian.PutUint32(data[1:], uint32(schemaId))
+			data = append(data, body...)
+
+			serializer, err := NewSerializer(configs.SerializerConfig{Format: tt.format}, schemaRegistry)
+			if err != nil {
+				t.Fatalf("serializer: %v", err)
+			}
+
+			envelope, err := serializer.Deserialize(ctx, "commands", data)
+			if err != nil {
+				t.Fatalf("deserialize: %v", err)
+			}
+
+			var payload request.TypingRequest
+			if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+
+			want := request.TypingRequest{ConversationId: "conversation", EntryId: "entry", Started: true}
+			if envelope.Type != request.CommandTyping || envelope.Id != "request" || payload != want {
+				t.Fatalf("got envelope %+v payload %+v", envelope, payload)
+			}
+		})
+	}
+}
+
+func TestSchemaSerializerFallsBackToJSON(t *testing.T) {
+	_, schemaRegistry := newStubSchemaRegistry(t)
+
+	serializer, err := NewSerializer(configs.SerializerConfig{Format: SerializerFormatAvro}, schemaRegistry)
+	if err != nil {
+		t.Fatalf("serializer: %v", err)
+	}
+
+	envelope, err := serializer.Deserialize(context.Background(), "commands", []byte(`{"type":"conversation.close","version":1,"id":"request","payload":{}}`))
+	if err != nil {
+		t.Fatalf("deserialize: %v", err)
+	}
+
+	if envelope.Type != request.CommandCloseConversation || envelope.Id != "request" {
+		t.Fatalf("got envelope %+v", envelope)
+	}
+}
+
+func TestSchemaRegistryReportsErrors(t *testing.T) {
+	_, schemaRegistry := newStubSchemaRegistry(t)
+
+	if _, _, err := schemaRegistry.Schema(context.Background(), 42); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("got %v, want a 404 error", err)
+	}
+
+	_, err := NewSchemaRegistry(configs.SerializerConfig{SchemaRegistryUrl: "http://127.0.0.1:1"}, NewHTTPClient()).Register(context.Background(), "subject", SchemaTypeAvro, "{}")
+	if err == nil {
+		t.Fatal("register against an unreachable registry succeeded")
+	}
+}
+
+func TestSchemaRecordOfUsesTaggedFieldNumbers(t *testing.T) {
+	record, err := schemaRecordOf(reflect.TypeOf(struct {
+		First  string `json:"first" protobuf:"7"`
+		Second bool   `json:"second" protobuf:"2"`
+	}{}))
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if record.Fields[0].Number != 7 || record.Fields[1].Number != 2 {
+		t.Fatalf("got fields %+v", record.Fields)
+	}
+
+	invalid := map[string]interface{}{
+		"missing": struct {
+			First string `json:"first"`
+		}{},
+		"duplicate": struct {
+			First  string `json:"first" protobuf:"1"`
+			Second string `json:"second" protobuf:"1"`
+		}{},
+		"reserved": struct {
+			First string `json:"first" protobuf:"19000"`
+		}{},
+		"zero": struct {
+			First string `json:"first" protobuf:"0"`
+		}{},
+	}
+	for name, payload := range invalid {
+		if _, err := schemaRecordOf(reflect.TypeOf(payload)); err == nil {
+			t.Fatalf("%s: accepted the field numbers", name)
+		}
+	}
+}
+
+func TestProtobufCodecDecodesLibraryFeatures(t *testing.T) {
+	schema := `syntax = "proto3";
+package salesforce.sse.worker;
+
+import "google/protobuf/timestamp.proto";
+
+message Unrelated {
+  string name = 1;
+}
+
+message ConversationClose {
+  string type = 1;
+  repeated string tags = 6;
+  google.protobuf.Timestamp created = 7;
+  map<string, int64> counters = 8;
+}
+`
+
+	codec := protobufCodec{}
+	descriptor, err := codec.parse(schema, []int{1})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	message := dynamicpb.NewMessage(descriptor.(*protobufDescriptor).message)
+	if err := protojson.Unmarshal([]byte(`{"type":"conversation.close","tags":["a","b"],"created":"2023-11-14T22:13:20Z","counters":{"x":3}}`), message); err != nil {
+		t.Fatalf("build message: %v", err)
+	}
+
+	body, err := proto.Marshal(message)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	data := append(appendProtobufMessageIndexes(nil, []int{1}), body...)
+	messageIndexes, body, err := codec.readMessageIndexes(data)
+	if err != nil || !reflect.DeepEqual(messageIndexes, []int{1}) {
+		t.Fatalf("got message indexes %v and error %v", messageIndexes, err)
+	}
+
+	value, err := descriptor.decode(body)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"type":     "conversation.close",
+		"tags":     []interface{}{"a", "b"},
+		"created":  map[string]interface{}{"seconds": int64(1700000000), "nanos": int64(0)},
+		"counters": map[string]interface{}{"x": int64(3)},
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Fatalf("got %#v, want %#v", value, want)
+	}
+
+	if _, err := codec.parse(schema, []int{2}); err == nil {
+		t.Fatal("parsed a missing message index")
+	}
+	if _, err := codec.parse("message {", []int{0}); err == nil {
+		t.Fatal("parsed an invalid schema")
+	}
+}
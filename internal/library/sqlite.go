@@ -0,0 +1,30 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/mattn/go-sqlite3"
+	"salesforce-sse-worker/configs"
+)
+
+func NewSqliteDatabase(storageConfig configs.StorageConfig, lifecycle Lifecycle) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL", storageConfig.SqlitePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s: %w", storageConfig.SqlitePath, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	lifecycle.Append(LifecycleHook{
+		Name:  "sqlite",
+		Order: LifecycleOrderStorage,
+		OnStart: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+		OnStop: func(ctx context.Context) error {
+			return db.Close()
+		},
+	})
+
+	return db, nil
+}
@@ -2,8 +2,10 @@ package library
 
 import (
 	"context"
+	"fmt"
 	"github.com/r3labs/sse/v2"
 	"log/slog"
+	"net/http"
 )
 
 type (
@@ -11,28 +13,57 @@ type (
 		Start(ctx context.Context) error
 	}
 
+	SSEEventHandler func(eventType string, data []byte)
+
+	SSEConnectHandler func()
+
 	SSEClientImpl struct {
-		url     string
-		headers map[string]string
-		client  *sse.Client
+		url       string
+		headers   map[string]string
+		client    *sse.Client
+		onConnect SSEConnectHandler
+		onEvent   SSEEventHandler
 	}
 )
 
-func NewSSEClient(url string, headers map[string]string) SSEClient {
+func NewSSEClient(url string, headers map[string]string, onConnect SSEConnectHandler, onEvent SSEEventHandler) SSEClient {
 	client := sse.NewClient(url)
 	for k, v := range headers {
 		client.Headers[k] = v
 	}
-	return &SSEClientImpl{
-		url:     url,
-		headers: headers,
-		client:  client,
+
+	s := &SSEClientImpl{
+		url:       url,
+		headers:   headers,
+		client:    client,
+		onConnect: onConnect,
+		onEvent:   onEvent,
+	}
+	client.ResponseValidator = s.validateResponse
+
+	return s
+}
+
+func (s *SSEClientImpl) validateResponse(client *sse.Client, resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return fmt.Errorf("could not connect to stream: %s", http.StatusText(resp.StatusCode))
+	}
+
+	if s.onConnect != nil {
+		s.onConnect()
 	}
+
+	return nil
 }
 
 func (s *SSEClientImpl) Start(ctx context.Context) error {
 	err := s.client.SubscribeWithContext(ctx, "", func(msg *sse.Event) {
 		slog.InfoContext(ctx, "Received message", "url", s.url, "data", msg.Data)
+
+		if s.onEvent != nil {
+			s.onEvent(string(msg.Event), msg.Data)
+		}
 	})
 
 	if err != nil {
@@ -0,0 +1,67 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSSEClientReportsConnectBeforeEvents(t *testing.T) {
+	send := make(chan string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case data := <-send:
+				_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connected := make(chan struct{}, 1)
+	events := make(chan string, 1)
+	client := NewSSEClient(server.URL, map[string]string{}, func() {
+		connected <- struct{}{}
+	}, func(eventType string, data []byte) {
+		events <- eventType + " " + string(data)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = client.Start(ctx)
+	}()
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connect was not reported before the first event")
+	}
+
+	send <- "hello"
+	select {
+	case event := <-events:
+		if event != "message hello" {
+			t.Fatalf("got event %q", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	cancel()
+	server.CloseClientConnections()
+	<-done
+}
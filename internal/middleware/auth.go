@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"strings"
+)
+
+const (
+	principalKey = "principal"
+	bearerPrefix = "Bearer "
+)
+
+type (
+	AuthMiddleware interface {
+		Require(scope string) echo.MiddlewareFunc
+	}
+
+	AuthMiddlewareImpl struct {
+		authConfig       configs.AuthConfig
+		jwtVerifier      library.JWTVerifier
+		apiKeyRepository repository.ApiKeyRepository
+	}
+)
+
+func NewAuthMiddleware(authConfig configs.AuthConfig, jwtVerifier library.JWTVerifier, apiKeyRepository repository.ApiKeyRepository) AuthMiddleware {
+	return &AuthMiddlewareImpl{
+		authConfig:       authConfig,
+		jwtVerifier:      jwtVerifier,
+		apiKeyRepository: apiKeyRepository,
+	}
+}
+
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func GetPrincipal(e echo.Context) (model.Principal, bool) {
+	principal, ok := e.Get(principalKey).(model.Principal)
+	return principal, ok
+}
+
+func (a *AuthMiddlewareImpl) Require(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(e echo.Context) error {
+			if !a.authConfig.Enabled {
+				return next(e)
+			}
+
+			principal, ok, err := a.authenticate(e)
+			if err != nil {
+				slog.ErrorContext(e.Request().Context(), "Failed to authenticate request", slog.Any("error", err))
+				return e.JSON(503, map[string]string{"error": "Service unavailable", "details": "failed to verify credentials"})
+			}
+			if !ok {
+				return e.JSON(401, map[string]string{"error": "Unauthorized"})
+			}
+
+			if !principal.HasScope(scope) {
+				return e.JSON(403, map[string]string{"error": "Forbidden", "details": "missing scope " + scope})
+			}
+
+			e.Set(principalKey, principal)
+
+			return next(e)
+		}
+	}
+}
+
+func (a *AuthMiddlewareImpl) authenticate(e echo.Context) (model.Principal, bool, error) {
+	ctx := e.Request().Context()
+
+	if key := e.Request().Header.Get(a.authConfig.ApiKeyHeader); key != "" {
+		apiKey, err := a.apiKeyRepository.FindOneByHash(ctx, HashApiKey(key))
+		if err != nil {
+			return model.Principal{}, false, fmt.Errorf("failed to find api key: %w", err)
+		}
+
+		if apiKey == nil || apiKey.Disabled {
+			return model.Principal{}, false, nil
+		}
+
+		return model.Principal{Subject: apiKey.Name, Method: "api_key", Scopes: apiKey.Scopes}, true, nil
+	}
+
+	authorization := e.Request().Header.Get(echo.HeaderAuthorization)
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return model.Principal{}, false, nil
+	}
+
+	subject, scopes, err := a.jwtVerifier.Verify(strings.TrimPrefix(authorization, bearerPrefix))
+	if err != nil {
+		slog.InfoContext(ctx, "Rejected bearer token", slog.Any("error", err))
+		return model.Principal{}, false, nil
+	}
+
+	return model.Principal{Subject: subject, Method: "jwt", Scopes: scopes}, true, nil
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	BackpressureMiddleware interface {
+		Guard() echo.MiddlewareFunc
+	}
+
+	BackpressureMiddlewareImpl struct {
+		lag                atomic.Int64
+		lagRefreshedAt     atomic.Int64
+		queueConfig        configs.QueueConfig
+		backpressureConfig configs.BackpressureConfig
+		queueAdmin         library.QueueAdmin
+	}
+)
+
+func NewBackpressureMiddleware(queueConfig configs.QueueConfig, backpressureConfig configs.BackpressureConfig, queueAdmin library.QueueAdmin, lifecycle library.Lifecycle) BackpressureMiddleware {
+	b := &BackpressureMiddlewareImpl{
+		queueConfig:        queueConfig,
+		backpressureConfig: backpressureConfig,
+		queueAdmin:         queueAdmin,
+	}
+
+	if backpressureConfig.Enabled {
+		lifecycle.AppendBackground("backpressure lag refresher", library.LifecycleOrderService, func(ctx context.Context) error {
+			b.refreshLag(ctx)
+			return nil
+		})
+	}
+
+	return b
+}
+
+func (b *BackpressureMiddlewareImpl) Guard() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(e echo.Context) error {
+			if !b.backpressureConfig.Enabled {
+				return next(e)
+			}
+
+			if b.lagStale() {
+				return next(e)
+			}
+
+			if lag := b.lag.Load(); lag > b.backpressureConfig.LagThreshold {
+				e.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(b.backpressureConfig.RetryAfter))
+				return e.JSON(503, map[string]string{"error": "Service overloaded", "details": "consumer lag " + strconv.FormatInt(lag, 10)})
+			}
+
+			return next(e)
+		}
+	}
+}
+
+func (b *BackpressureMiddlewareImpl) refreshLag(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(b.backpressureConfig.RefreshInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		lag, err := b.queueAdmin.ConsumerGroupLag(ctx, b.queueConfig.GroupName, b.queueConfig.Topics[0])
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to read consumer group lag", slog.Any("error", err))
+		} else {
+			b.lag.Store(lag)
+			b.lagRefreshedAt.Store(time.Now().UnixNano())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *BackpressureMiddlewareImpl) lagStale() bool {
+	refreshedAt := b.lagRefreshedAt.Load()
+	if refreshedAt == 0 {
+		return true
+	}
+
+	return time.Since(time.Unix(0, refreshedAt)) > time.Duration(b.backpressureConfig.MaxLagAge)*time.Millisecond
+}
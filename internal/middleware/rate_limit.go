@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+	"salesforce-sse-worker/configs"
+	"time"
+)
+
+type (
+	RateLimitMiddleware interface {
+		Limit() echo.MiddlewareFunc
+	}
+
+	RateLimitMiddlewareImpl struct {
+		rateLimitConfig configs.RateLimitConfig
+		store           echomiddleware.RateLimiterStore
+	}
+)
+
+func NewRateLimitMiddleware(rateLimitConfig configs.RateLimitConfig) RateLimitMiddleware {
+	return &RateLimitMiddlewareImpl{
+		rateLimitConfig: rateLimitConfig,
+		store: echomiddleware.NewRateLimiterMemoryStoreWithConfig(echomiddleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(rateLimitConfig.Rate),
+			Burst:     rateLimitConfig.Burst,
+			ExpiresIn: time.Duration(rateLimitConfig.ExpiresIn) * time.Millisecond,
+		}),
+	}
+}
+
+func (r *RateLimitMiddlewareImpl) Limit() echo.MiddlewareFunc {
+	return echomiddleware.RateLimiterWithConfig(echomiddleware.RateLimiterConfig{
+		Skipper: func(e echo.Context) bool {
+			return !r.rateLimitConfig.Enabled
+		},
+		Store: r.store,
+		IdentifierExtractor: func(e echo.Context) (string, error) {
+			if principal, ok := GetPrincipal(e); ok {
+				return principal.Method + ":" + principal.Subject, nil
+			}
+
+			return "ip:" + e.RealIP(), nil
+		},
+		DenyHandler: func(e echo.Context, identifier string, err error) error {
+			return e.JSON(429, map[string]string{"error": "Too many requests"})
+		},
+		ErrorHandler: func(e echo.Context, err error) error {
+			return e.JSON(403, map[string]string{"error": "Unable to identify caller"})
+		},
+	})
+}
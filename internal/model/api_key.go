@@ -0,0 +1,15 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"time"
+)
+
+type ApiKey struct {
+	Id        bson.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string        `json:"name" bson:"name"`
+	KeyHash   string        `json:"-" bson:"keyHash"`
+	Scopes    []string      `json:"scopes" bson:"scopes"`
+	Disabled  bool          `json:"disabled" bson:"disabled"`
+	CreatedAt time.Time     `json:"createdAt" bson:"createdAt"`
+}
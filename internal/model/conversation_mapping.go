@@ -6,4 +6,7 @@ type ConversationMapping struct {
 	Id        bson.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	Token     string        `json:"token" bson:"token"`
 	Partition int           `json:"partition" bson:"partition"`
+	Orphaned  bool          `json:"orphaned" bson:"orphaned"`
+	KeyId     string        `json:"-" bson:"keyId,omitempty"`
+	DataKey   []byte        `json:"-" bson:"dataKey,omitempty"`
 }
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"time"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+)
+
+type OutboxMessage struct {
+	Id        bson.ObjectID  `json:"id" bson:"_id"`
+	Topic     string         `json:"topic" bson:"topic"`
+	Key       []byte         `json:"key,omitempty" bson:"key,omitempty"`
+	Value     []byte         `json:"value" bson:"value"`
+	Headers   []OutboxHeader `json:"headers,omitempty" bson:"headers,omitempty"`
+	Status    string         `json:"status" bson:"status"`
+	Attempts  int            `json:"attempts" bson:"attempts"`
+	LastError string         `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	Partition int32          `json:"partition" bson:"partition"`
+	Manual    bool           `json:"manual,omitempty" bson:"manual,omitempty"`
+	Offset    int64          `json:"offset" bson:"offset"`
+	CreatedAt time.Time      `json:"createdAt" bson:"createdAt"`
+	SentAt    *time.Time     `json:"sentAt,omitempty" bson:"sentAt,omitempty"`
+}
+
+type OutboxHeader struct {
+	Key   string `json:"key" bson:"key"`
+	Value []byte `json:"value" bson:"value"`
+}
+
+type OutboxLease struct {
+	Id        string    `json:"id" bson:"_id"`
+	Owner     string    `json:"owner" bson:"owner"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "slices"
+
+const (
+	ScopeAdmin              = "admin"
+	ScopeConversationCreate = "conversation:create"
+	ScopeConversationWrite  = "conversation:write"
+)
+
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Scopes  []string `json:"scopes"`
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, ScopeAdmin) || slices.Contains(p.Scopes, scope)
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type SchemaMigration struct {
+	Version     int       `json:"version" bson:"_id"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"appliedAt" bson:"appliedAt"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	SseStatusConnecting   = "connecting"
+	SseStatusConnected    = "connected"
+	SseStatusDisconnected = "disconnected"
+	SseStatusRevoked      = "revoked"
+	SseStatusError        = "error"
+)
+
+type SseStatus struct {
+	Partition int       `json:"partition" bson:"_id"`
+	Status    string    `json:"status" bson:"status"`
+	Owner     string    `json:"owner" bson:"owner"`
+	Error     string    `json:"error,omitempty" bson:"error,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
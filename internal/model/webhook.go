@@ -0,0 +1,40 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"time"
+)
+
+const (
+	ConversationEventCreated = "conversation.created"
+	ConversationEventFailed  = "conversation.failed"
+	ConversationEventRouted  = "conversation.routed"
+	ConversationEventClosed  = "conversation.closed"
+
+	WebhookStatusPending   = "pending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusFailed    = "failed"
+)
+
+type ConversationCallback struct {
+	Id             bson.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	ConversationId string        `json:"conversationId" bson:"conversationId"`
+	CallbackUrl    string        `json:"callbackUrl" bson:"callbackUrl"`
+	CreatedAt      time.Time     `json:"createdAt" bson:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	Id             bson.ObjectID `json:"id" bson:"_id"`
+	ConversationId string        `json:"conversationId" bson:"conversationId"`
+	Event          string        `json:"event" bson:"event"`
+	Url            string        `json:"url" bson:"url"`
+	Payload        string        `json:"payload" bson:"payload"`
+	Status         string        `json:"status" bson:"status"`
+	Attempts       int           `json:"attempts" bson:"attempts"`
+	LastStatusCode int           `json:"lastStatusCode,omitempty" bson:"lastStatusCode,omitempty"`
+	LastError      string        `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt      time.Time     `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt" bson:"updatedAt"`
+	NextAttemptAt  time.Time     `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	DeliveredAt    *time.Time    `json:"deliveredAt,omitempty" bson:"deliveredAt,omitempty"`
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+)
+
+const (
+	apiKey = "api_key"
+)
+
+type (
+	ApiKeyRepository interface {
+		FindOneByHash(ctx context.Context, keyHash string) (*model.ApiKey, error)
+		Upsert(ctx context.Context, data model.ApiKey) (*mongo.UpdateResult, error)
+	}
+
+	ApiKeyRepositoryImpl struct {
+		MongoDatabase library.MongoDatabase
+	}
+)
+
+func NewApiKeyRepository(mongoDatabase library.MongoDatabase) ApiKeyRepository {
+	return &ApiKeyRepositoryImpl{
+		MongoDatabase: mongoDatabase,
+	}
+}
+
+func (s *ApiKeyRepositoryImpl) FindOneByHash(ctx context.Context, keyHash string) (*model.ApiKey, error) {
+	query := map[string]interface{}{
+		"keyHash": keyHash,
+	}
+
+	singleResult := s.MongoDatabase.FindOne(ctx, apiKey, query)
+	if err := singleResult.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result model.ApiKey
+	if err := singleResult.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *ApiKeyRepositoryImpl) Upsert(ctx context.Context, data model.ApiKey) (*mongo.UpdateResult, error) {
+	query := map[string]interface{}{
+		"keyHash": data.KeyHash,
+	}
+
+	return s.MongoDatabase.ReplaceOne(ctx, apiKey, query, data, options.Replace().SetUpsert(true))
+}
@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"salesforce-sse-worker/internal/library"
 	"salesforce-sse-worker/internal/model"
+	"sync"
 )
 
 const (
@@ -16,20 +20,46 @@ type (
 		FindAll(ctx context.Context) ([]model.ConversationMapping, error)
 		FindOneByPartition(ctx context.Context, partition int) (*model.ConversationMapping, error)
 		Upsert(ctx context.Context, data model.ConversationMapping) (*mongo.UpdateResult, error)
+		Watch(ctx context.Context, onChange func(*model.ConversationMapping)) error
+		ReEncrypt(ctx context.Context) (int, error)
+		DeleteOrphaned(ctx context.Context) (int64, error)
 	}
 
 	ConversationMappingRepositoryImpl struct {
 		MongoDatabase library.MongoDatabase
+		Cipher        library.Cipher
+	}
+
+	mappingWatchers struct {
+		mu       sync.Mutex
+		next     int
+		onChange map[int]func(*model.ConversationMapping)
 	}
 )
 
-func NewConversationMappingRepository(mongoDatabase library.MongoDatabase) ConversationMappingRepository {
+func NewMongoConversationMappingRepository(mongoDatabase library.MongoDatabase, cipher library.Cipher) ConversationMappingRepository {
 	return &ConversationMappingRepositoryImpl{
 		MongoDatabase: mongoDatabase,
+		Cipher:        cipher,
 	}
 }
 
 func (s *ConversationMappingRepositoryImpl) FindAll(ctx context.Context) ([]model.ConversationMapping, error) {
+	results, err := s.findAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if err := decryptMapping(s.Cipher, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (s *ConversationMappingRepositoryImpl) findAll(ctx context.Context) ([]model.ConversationMapping, error) {
 	query := map[string]interface{}{}
 
 	cursor, err := s.MongoDatabase.Find(ctx, conversationMapping, query)
@@ -69,6 +99,10 @@ func (s *ConversationMappingRepositoryImpl) FindOneByPartition(ctx context.Conte
 		return nil, err
 	}
 
+	if err := decryptMapping(s.Cipher, &result); err != nil {
+		return nil, err
+	}
+
 	return &result, nil
 }
 
@@ -77,5 +111,175 @@ func (s *ConversationMappingRepositoryImpl) Upsert(ctx context.Context, data mod
 		"partition": data.Partition,
 	}
 
-	return s.MongoDatabase.ReplaceOne(ctx, conversationMapping, query, data)
+	if err := encryptMapping(s.Cipher, &data); err != nil {
+		return nil, err
+	}
+
+	return s.MongoDatabase.ReplaceOne(ctx, conversationMapping, query, data, options.Replace().SetUpsert(true))
+}
+
+func (s *ConversationMappingRepositoryImpl) Watch(ctx context.Context, onChange func(*model.ConversationMapping)) error {
+	changeStream, err := s.MongoDatabase.Watch(ctx, conversationMapping, []interface{}{})
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer changeStream.Close(ctx)
+
+	for changeStream.Next(ctx) {
+		var event struct {
+			OperationType string                     `bson:"operationType"`
+			FullDocument  *model.ConversationMapping `bson:"fullDocument"`
+		}
+		if err := changeStream.Decode(&event); err != nil {
+			return err
+		}
+
+		if event.OperationType == "delete" || event.FullDocument == nil {
+			onChange(nil)
+			continue
+		}
+
+		if err := decryptMapping(s.Cipher, event.FullDocument); err != nil {
+			return err
+		}
+
+		onChange(event.FullDocument)
+	}
+
+	return changeStream.Err()
+}
+
+func (s *ConversationMappingRepositoryImpl) ReEncrypt(ctx context.Context) (int, error) {
+	if !s.Cipher.Enabled() {
+		return 0, library.ErrEncryptionDisabled
+	}
+
+	mappings, err := s.findAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mapping := range mappings {
+		if mapping.KeyId == s.Cipher.ActiveKeyId() {
+			continue
+		}
+
+		if err := decryptMapping(s.Cipher, &mapping); err != nil {
+			return count, fmt.Errorf("failed to decrypt partition %d: %w", mapping.Partition, err)
+		}
+
+		if err := encryptMapping(s.Cipher, &mapping); err != nil {
+			return count, fmt.Errorf("failed to encrypt partition %d: %w", mapping.Partition, err)
+		}
+
+		query := map[string]interface{}{
+			"_id": mapping.Id,
+		}
+
+		if _, err := s.MongoDatabase.ReplaceOne(ctx, conversationMapping, query, mapping); err != nil {
+			return count, fmt.Errorf("failed to update partition %d: %w", mapping.Partition, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func encryptMapping(cipher library.Cipher, data *model.ConversationMapping) error {
+	data.KeyId, data.DataKey = "", nil
+	if !cipher.Enabled() {
+		return nil
+	}
+
+	value, err := cipher.Encrypt([]byte(data.Token))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	data.Token = base64.StdEncoding.EncodeToString(value.Ciphertext)
+	data.KeyId = value.KeyId
+	data.DataKey = value.DataKey
+
+	return nil
+}
+
+func decryptMapping(cipher library.Cipher, data *model.ConversationMapping) error {
+	if data.KeyId == "" {
+		return nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(data.Token)
+	if err != nil {
+		return fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	token, err := cipher.Decrypt(library.EncryptedValue{
+		KeyId:      data.KeyId,
+		DataKey:    data.DataKey,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return err
+	}
+
+	data.Token = string(token)
+	data.KeyId, data.DataKey = "", nil
+
+	return nil
+}
+
+func (s *ConversationMappingRepositoryImpl) DeleteOrphaned(ctx context.Context) (int64, error) {
+	query := map[string]interface{}{
+		"orphaned": true,
+	}
+
+	result, err := s.MongoDatabase.DeleteMany(ctx, conversationMapping, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func newMappingWatchers() *mappingWatchers {
+	return &mappingWatchers{onChange: map[int]func(*model.ConversationMapping){}}
+}
+
+func (w *mappingWatchers) watch(ctx context.Context, onChange func(*model.ConversationMapping)) error {
+	w.mu.Lock()
+	id := w.next
+	w.next++
+	w.onChange[id] = onChange
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		delete(w.onChange, id)
+		w.mu.Unlock()
+	}()
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func (w *mappingWatchers) notify(mapping *model.ConversationMapping) {
+	w.mu.Lock()
+	callbacks := make([]func(*model.ConversationMapping), 0, len(w.onChange))
+	for _, onChange := range w.onChange {
+		callbacks = append(callbacks, onChange)
+	}
+	w.mu.Unlock()
+
+	for _, onChange := range callbacks {
+		if mapping == nil {
+			onChange(nil)
+			continue
+		}
+
+		copied := *mapping
+		onChange(&copied)
+	}
 }
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"sort"
+	"sync"
+)
+
+type MemoryConversationMappingRepositoryImpl struct {
+	mu       sync.RWMutex
+	mappings map[int]model.ConversationMapping
+	Cipher   library.Cipher
+	watchers *mappingWatchers
+}
+
+func NewMemoryConversationMappingRepository(cipher library.Cipher) ConversationMappingRepository {
+	return &MemoryConversationMappingRepositoryImpl{
+		mappings: map[int]model.ConversationMapping{},
+		Cipher:   cipher,
+		watchers: newMappingWatchers(),
+	}
+}
+
+func (s *MemoryConversationMappingRepositoryImpl) FindAll(ctx context.Context) ([]model.ConversationMapping, error) {
+	s.mu.RLock()
+	results := make([]model.ConversationMapping, 0, len(s.mappings))
+	for _, mapping := range s.mappings {
+		results = append(results, mapping)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Partition < results[j].Partition
+	})
+
+	for i := range results {
+		if err := decryptMapping(s.Cipher, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (s *MemoryConversationMappingRepositoryImpl) FindOneByPartition(ctx context.Context, partition int) (*model.ConversationMapping, error) {
+	s.mu.RLock()
+	result, ok := s.mappings[partition]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	if err := decryptMapping(s.Cipher, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *MemoryConversationMappingRepositoryImpl) Upsert(ctx context.Context, data model.ConversationMapping) (*mongo.UpdateResult, error) {
+	stored := data
+	if err := encryptMapping(s.Cipher, &stored); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	result := &mongo.UpdateResult{Acknowledged: true}
+	if existing, ok := s.mappings[data.Partition]; ok {
+		stored.Id = existing.Id
+		result.MatchedCount, result.ModifiedCount = 1, 1
+	} else {
+		if stored.Id.IsZero() {
+			stored.Id = bson.NewObjectID()
+		}
+		result.UpsertedCount, result.UpsertedID = 1, stored.Id
+	}
+	s.mappings[data.Partition] = stored
+	s.mu.Unlock()
+
+	data.Id = stored.Id
+	s.watchers.notify(&data)
+
+	return result, nil
+}
+
+func (s *MemoryConversationMappingRepositoryImpl) Watch(ctx context.Context, onChange func(*model.ConversationMapping)) error {
+	return s.watchers.watch(ctx, onChange)
+}
+
+func (s *MemoryConversationMappingRepositoryImpl) ReEncrypt(ctx context.Context) (int, error) {
+	if !s.Cipher.Enabled() {
+		return 0, library.ErrEncryptionDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for partition, mapping := range s.mappings {
+		if mapping.KeyId == s.Cipher.ActiveKeyId() {
+			continue
+		}
+
+		if err := decryptMapping(s.Cipher, &mapping); err != nil {
+			return count, fmt.Errorf("failed to decrypt partition %d: %w", partition, err)
+		}
+
+		if err := encryptMapping(s.Cipher, &mapping); err != nil {
+			return count, fmt.Errorf("failed to encrypt partition %d: %w", partition, err)
+		}
+
+		s.mappings[partition] = mapping
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *MemoryConversationMappingRepositoryImpl) DeleteOrphaned(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	var deleted int64
+	for partition, mapping := range s.mappings {
+		if mapping.Orphaned {
+			delete(s.mappings, partition)
+			deleted++
+		}
+	}
+	s.mu.Unlock()
+
+	if deleted > 0 {
+		s.watchers.notify(nil)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/repository/repositorytest"
+	"testing"
+)
+
+func TestMemoryConversationMappingRepository(t *testing.T) {
+	repositorytest.ConversationMappingRepository(t, func(t *testing.T, cipher library.Cipher) repository.ConversationMappingRepository {
+		return repository.NewMemoryConversationMappingRepository(cipher)
+	})
+}
@@ -0,0 +1,43 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"os"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/repository/repositorytest"
+	"testing"
+	"time"
+)
+
+func TestMongoConversationMappingRepositoryInMemory(t *testing.T) {
+	repositorytest.ConversationMappingRepository(t, func(t *testing.T, cipher library.Cipher) repository.ConversationMappingRepository {
+		return repository.NewMongoConversationMappingRepository(library.NewMemoryMongoDatabase(), cipher)
+	})
+}
+
+func TestMongoConversationMappingRepository(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+	repositorytest.ConversationMappingRepository(t, func(t *testing.T, cipher library.Cipher) repository.ConversationMappingRepository {
+		name := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+		t.Cleanup(func() { _ = client.Database(name).Drop(context.Background()) })
+
+		mongoDatabase := library.NewMongoDatabase(configs.MongoConfig{DatabaseName: name}, client, library.NewLifecycle(configs.LifecycleConfig{}))
+
+		return repository.NewMongoConversationMappingRepository(mongoDatabase, cipher)
+	})
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+)
+
+const (
+	sqliteConversationMappingSchema = `CREATE TABLE IF NOT EXISTS conversation_mapping (
+	id TEXT PRIMARY KEY,
+	partition INTEGER NOT NULL UNIQUE,
+	token TEXT NOT NULL,
+	orphaned INTEGER NOT NULL DEFAULT 0,
+	key_id TEXT NOT NULL DEFAULT '',
+	data_key BLOB
+)`
+	sqliteConversationMappingColumns = "id, partition, token, orphaned, key_id, data_key"
+)
+
+type (
+	SqliteConversationMappingRepositoryImpl struct {
+		DB       *sql.DB
+		Cipher   library.Cipher
+		watchers *mappingWatchers
+	}
+
+	sqliteScanner interface {
+		Scan(dest ...interface{}) error
+	}
+)
+
+func NewSqliteConversationMappingRepository(db *sql.DB, cipher library.Cipher) (ConversationMappingRepository, error) {
+	if _, err := db.Exec(sqliteConversationMappingSchema); err != nil {
+		return nil, fmt.Errorf("failed to create conversation_mapping table: %w", err)
+	}
+
+	return &SqliteConversationMappingRepositoryImpl{
+		DB:       db,
+		Cipher:   cipher,
+		watchers: newMappingWatchers(),
+	}, nil
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) FindAll(ctx context.Context) ([]model.ConversationMapping, error) {
+	results, err := s.findAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if err := decryptMapping(s.Cipher, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) findAll(ctx context.Context) ([]model.ConversationMapping, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+sqliteConversationMappingColumns+" FROM conversation_mapping ORDER BY partition")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.ConversationMapping
+	for rows.Next() {
+		mapping, err := scanConversationMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *mapping)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) FindOneByPartition(ctx context.Context, partition int) (*model.ConversationMapping, error) {
+	row := s.DB.QueryRowContext(ctx, "SELECT "+sqliteConversationMappingColumns+" FROM conversation_mapping WHERE partition = ?", partition)
+
+	result, err := scanConversationMapping(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, mongo.ErrNoDocuments
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decryptMapping(s.Cipher, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) Upsert(ctx context.Context, data model.ConversationMapping) (*mongo.UpdateResult, error) {
+	stored := data
+	if err := encryptMapping(s.Cipher, &stored); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &mongo.UpdateResult{Acknowledged: true}
+
+	var id string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM conversation_mapping WHERE partition = ?", stored.Partition).Scan(&id)
+	switch {
+	case err == nil:
+		if stored.Id, err = bson.ObjectIDFromHex(id); err != nil {
+			return nil, fmt.Errorf("failed to decode id of partition %d: %w", stored.Partition, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE conversation_mapping SET token = ?, orphaned = ?, key_id = ?, data_key = ? WHERE id = ?",
+			stored.Token, stored.Orphaned, stored.KeyId, stored.DataKey, id); err != nil {
+			return nil, err
+		}
+		result.MatchedCount, result.ModifiedCount = 1, 1
+	case errors.Is(err, sql.ErrNoRows):
+		if stored.Id.IsZero() {
+			stored.Id = bson.NewObjectID()
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO conversation_mapping ("+sqliteConversationMappingColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+			stored.Id.Hex(), stored.Partition, stored.Token, stored.Orphaned, stored.KeyId, stored.DataKey); err != nil {
+			return nil, err
+		}
+		result.UpsertedCount, result.UpsertedID = 1, stored.Id
+	default:
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	data.Id = stored.Id
+	s.watchers.notify(&data)
+
+	return result, nil
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) Watch(ctx context.Context, onChange func(*model.ConversationMapping)) error {
+	return s.watchers.watch(ctx, onChange)
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) ReEncrypt(ctx context.Context) (int, error) {
+	if !s.Cipher.Enabled() {
+		return 0, library.ErrEncryptionDisabled
+	}
+
+	mappings, err := s.findAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mapping := range mappings {
+		if mapping.KeyId == s.Cipher.ActiveKeyId() {
+			continue
+		}
+
+		if err := decryptMapping(s.Cipher, &mapping); err != nil {
+			return count, fmt.Errorf("failed to decrypt partition %d: %w", mapping.Partition, err)
+		}
+
+		if err := encryptMapping(s.Cipher, &mapping); err != nil {
+			return count, fmt.Errorf("failed to encrypt partition %d: %w", mapping.Partition, err)
+		}
+
+		if _, err := s.DB.ExecContext(ctx, "UPDATE conversation_mapping SET token = ?, key_id = ?, data_key = ? WHERE id = ?",
+			mapping.Token, mapping.KeyId, mapping.DataKey, mapping.Id.Hex()); err != nil {
+			return count, fmt.Errorf("failed to update partition %d: %w", mapping.Partition, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *SqliteConversationMappingRepositoryImpl) DeleteOrphaned(ctx context.Context) (int64, error) {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM conversation_mapping WHERE orphaned = 1")
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if deleted > 0 {
+		s.watchers.notify(nil)
+	}
+
+	return deleted, nil
+}
+
+func scanConversationMapping(scanner sqliteScanner) (*model.ConversationMapping, error) {
+	var (
+		mapping model.ConversationMapping
+		id      string
+	)
+
+	if err := scanner.Scan(&id, &mapping.Partition, &mapping.Token, &mapping.Orphaned, &mapping.KeyId, &mapping.DataKey); err != nil {
+		return nil, err
+	}
+
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id of partition %d: %w", mapping.Partition, err)
+	}
+	mapping.Id = objectId
+
+	return &mapping, nil
+}
@@ -0,0 +1,29 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/repository/repositorytest"
+	"testing"
+)
+
+func TestSqliteConversationMappingRepository(t *testing.T) {
+	repositorytest.ConversationMappingRepository(t, func(t *testing.T, cipher library.Cipher) repository.ConversationMappingRepository {
+		storageConfig := configs.StorageConfig{SqlitePath: filepath.Join(t.TempDir(), "mappings.db")}
+
+		db, err := library.NewSqliteDatabase(storageConfig, library.NewLifecycle(configs.LifecycleConfig{}))
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+
+		repo, err := repository.NewSqliteConversationMappingRepository(db, cipher)
+		if err != nil {
+			t.Fatalf("repository: %v", err)
+		}
+
+		return repo
+	})
+}
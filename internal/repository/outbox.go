@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"time"
+)
+
+const (
+	outboxMessage = "outbox_message"
+	outboxLease   = "outbox_lease"
+	relayLeaseId  = "relay"
+)
+
+type (
+	OutboxRepository interface {
+		FindPending(ctx context.Context, limit int) ([]model.OutboxMessage, error)
+		Upsert(ctx context.Context, data model.OutboxMessage) (*mongo.UpdateResult, error)
+		DeleteSent(ctx context.Context, before time.Time) (int64, error)
+		AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	}
+
+	OutboxRepositoryImpl struct {
+		MongoDatabase library.MongoDatabase
+	}
+)
+
+func NewOutboxRepository(mongoDatabase library.MongoDatabase) OutboxRepository {
+	return &OutboxRepositoryImpl{
+		MongoDatabase: mongoDatabase,
+	}
+}
+
+func (s *OutboxRepositoryImpl) FindPending(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	query := map[string]interface{}{
+		"status": model.OutboxStatusPending,
+	}
+
+	cursor, err := s.MongoDatabase.Find(ctx, outboxMessage, query, options.Find().SetSort(map[string]interface{}{"_id": 1}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []model.OutboxMessage{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *OutboxRepositoryImpl) Upsert(ctx context.Context, data model.OutboxMessage) (*mongo.UpdateResult, error) {
+	query := map[string]interface{}{
+		"_id": data.Id,
+	}
+
+	return s.MongoDatabase.ReplaceOne(ctx, outboxMessage, query, data, options.Replace().SetUpsert(true))
+}
+
+func (s *OutboxRepositoryImpl) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	query := map[string]interface{}{
+		"status": model.OutboxStatusSent,
+		"sentAt": map[string]interface{}{"$lt": before},
+	}
+
+	result, err := s.MongoDatabase.DeleteMany(ctx, outboxMessage, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (s *OutboxRepositoryImpl) AcquireLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	query := map[string]interface{}{
+		"_id": relayLeaseId,
+		"$or": []interface{}{
+			map[string]interface{}{"owner": owner},
+			map[string]interface{}{"expiresAt": map[string]interface{}{"$lt": now}},
+		},
+	}
+
+	_, err := s.MongoDatabase.ReplaceOne(ctx, outboxLease, query, model.OutboxLease{
+		Id:        relayLeaseId,
+		Owner:     owner,
+		ExpiresAt: now.Add(ttl),
+	}, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,228 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"testing"
+	"time"
+)
+
+type NewConversationMappingRepository func(t *testing.T, cipher library.Cipher) repository.ConversationMappingRepository
+
+func ConversationMappingRepository(t *testing.T, newRepository NewConversationMappingRepository) {
+	t.Run("upsert inserts then replaces", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t, disabledCipher(t))
+
+		inserted, err := repo.Upsert(ctx, model.ConversationMapping{Partition: 1, Token: "first"})
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		if inserted.UpsertedCount != 1 || inserted.MatchedCount != 0 {
+			t.Fatalf("insert: got upserted %d matched %d", inserted.UpsertedCount, inserted.MatchedCount)
+		}
+
+		first, err := repo.FindOneByPartition(ctx, 1)
+		if err != nil {
+			t.Fatalf("find after insert: %v", err)
+		}
+		if first.Id.IsZero() {
+			t.Fatal("find after insert: id was not assigned")
+		}
+
+		replaced, err := repo.Upsert(ctx, model.ConversationMapping{Partition: 1, Token: "second", Orphaned: true})
+		if err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if replaced.MatchedCount != 1 || replaced.UpsertedCount != 0 {
+			t.Fatalf("replace: got matched %d upserted %d", replaced.MatchedCount, replaced.UpsertedCount)
+		}
+
+		second, err := repo.FindOneByPartition(ctx, 1)
+		if err != nil {
+			t.Fatalf("find after replace: %v", err)
+		}
+		if second.Token != "second" || !second.Orphaned {
+			t.Fatalf("find after replace: got token %q orphaned %t", second.Token, second.Orphaned)
+		}
+		if second.Id != first.Id {
+			t.Fatalf("find after replace: id changed from %s to %s", first.Id.Hex(), second.Id.Hex())
+		}
+	})
+
+	t.Run("find one by missing partition", func(t *testing.T) {
+		repo := newRepository(t, disabledCipher(t))
+
+		if _, err := repo.FindOneByPartition(context.Background(), 42); !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Fatalf("got %v, want %v", err, mongo.ErrNoDocuments)
+		}
+	})
+
+	t.Run("find all", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t, disabledCipher(t))
+
+		mappings, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("find all on empty: %v", err)
+		}
+		if len(mappings) != 0 {
+			t.Fatalf("find all on empty: got %d mappings", len(mappings))
+		}
+
+		for partition := 0; partition < 3; partition++ {
+			upsert(t, repo, model.ConversationMapping{Partition: partition, Token: "token"})
+		}
+
+		mappings, err = repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("find all: %v", err)
+		}
+
+		seen := map[int]bool{}
+		for _, mapping := range mappings {
+			seen[mapping.Partition] = true
+		}
+		if len(mappings) != 3 || len(seen) != 3 {
+			t.Fatalf("find all: got %+v", mappings)
+		}
+	})
+
+	t.Run("delete orphaned", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t, disabledCipher(t))
+
+		upsert(t, repo, model.ConversationMapping{Partition: 0, Token: "kept"})
+		upsert(t, repo, model.ConversationMapping{Partition: 1, Token: "orphan", Orphaned: true})
+		upsert(t, repo, model.ConversationMapping{Partition: 2, Token: "orphan", Orphaned: true})
+
+		deleted, err := repo.DeleteOrphaned(ctx)
+		if err != nil {
+			t.Fatalf("delete orphaned: %v", err)
+		}
+		if deleted != 2 {
+			t.Fatalf("delete orphaned: got %d deleted, want 2", deleted)
+		}
+
+		mappings, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("find all: %v", err)
+		}
+		if len(mappings) != 1 || mappings[0].Partition != 0 {
+			t.Fatalf("find all: got %+v", mappings)
+		}
+	})
+
+	t.Run("re-encrypt without encryption", func(t *testing.T) {
+		repo := newRepository(t, disabledCipher(t))
+
+		if _, err := repo.ReEncrypt(context.Background()); !errors.Is(err, library.ErrEncryptionDisabled) {
+			t.Fatalf("got %v, want %v", err, library.ErrEncryptionDisabled)
+		}
+	})
+
+	t.Run("encrypted tokens round trip", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepository(t, enabledCipher(t))
+
+		upsert(t, repo, model.ConversationMapping{Partition: 5, Token: "secret"})
+
+		mapping, err := repo.FindOneByPartition(ctx, 5)
+		if err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		if mapping.Token != "secret" {
+			t.Fatalf("find: got token %q", mapping.Token)
+		}
+
+		mappings, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("find all: %v", err)
+		}
+		if len(mappings) != 1 || mappings[0].Token != "secret" {
+			t.Fatalf("find all: got %+v", mappings)
+		}
+
+		count, err := repo.ReEncrypt(ctx)
+		if err != nil {
+			t.Fatalf("re-encrypt: %v", err)
+		}
+		if count != 0 {
+			t.Fatalf("re-encrypt: got %d re-encrypted, want 0 under the active key", count)
+		}
+	})
+
+	t.Run("watch reports changes", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		repo := newRepository(t, disabledCipher(t))
+
+		changes := make(chan *model.ConversationMapping, 16)
+		watchErr := make(chan error, 1)
+		go func() {
+			watchErr <- repo.Watch(ctx, func(mapping *model.ConversationMapping) {
+				select {
+				case changes <- mapping:
+				default:
+				}
+			})
+		}()
+
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case mapping := <-changes:
+				if mapping == nil || mapping.Partition != 7 || mapping.Token != "watched" {
+					t.Fatalf("got change %+v", mapping)
+				}
+				return
+			case err := <-watchErr:
+				t.Fatalf("watch stopped: %v", err)
+			case <-ticker.C:
+				upsert(t, repo, model.ConversationMapping{Partition: 7, Token: "watched"})
+			case <-ctx.Done():
+				t.Fatal("no change was reported")
+			}
+		}
+	})
+}
+
+func upsert(t *testing.T, repo repository.ConversationMappingRepository, mapping model.ConversationMapping) {
+	t.Helper()
+
+	if _, err := repo.Upsert(context.Background(), mapping); err != nil {
+		t.Fatalf("upsert partition %d: %v", mapping.Partition, err)
+	}
+}
+
+func disabledCipher(t *testing.T) library.Cipher {
+	t.Helper()
+
+	cipher, err := library.NewAESCipher("", map[string]string{})
+	if err != nil {
+		t.Fatalf("cipher: %v", err)
+	}
+
+	return cipher
+}
+
+func enabledCipher(t *testing.T) library.Cipher {
+	t.Helper()
+
+	cipher, err := library.NewAESCipher("conformance", map[string]string{
+		"conformance": base64.StdEncoding.EncodeToString(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("cipher: %v", err)
+	}
+
+	return cipher
+}
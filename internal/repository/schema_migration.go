@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"time"
+)
+
+const (
+	schemaMigration = "schema_migration"
+)
+
+type (
+	SchemaMigrationRepository interface {
+		Migrations() []Migration
+		FindApplied(ctx context.Context) ([]model.SchemaMigration, error)
+		Apply(ctx context.Context, migration Migration) error
+	}
+
+	Migration struct {
+		Version     int
+		Description string
+		Collection  string
+		Up          func(ctx context.Context, mongoDatabase library.MongoDatabase) error
+	}
+
+	SchemaMigrationRepositoryImpl struct {
+		StorageConfig configs.StorageConfig
+		MongoDatabase library.MongoDatabase
+	}
+)
+
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "unique index on conversation_mapping.partition",
+		Collection:  conversationMapping,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			if err := deleteDuplicatePartitions(ctx, mongoDatabase); err != nil {
+				return err
+			}
+
+			return createIndex(ctx, mongoDatabase, conversationMapping, "partition_unique", bson.D{{Key: "partition", Value: 1}}, true)
+		},
+	},
+	{
+		Version:     2,
+		Description: "unique index on api_key.keyHash",
+		Collection:  apiKey,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			return createIndex(ctx, mongoDatabase, apiKey, "keyHash_unique", bson.D{{Key: "keyHash", Value: 1}}, true)
+		},
+	},
+	{
+		Version:     3,
+		Description: "unique index on conversation_callback.conversationId",
+		Collection:  conversationCallback,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			return createIndex(ctx, mongoDatabase, conversationCallback, "conversationId_unique", bson.D{{Key: "conversationId", Value: 1}}, true)
+		},
+	},
+	{
+		Version:     4,
+		Description: "index on webhook_delivery.status",
+		Collection:  webhookDelivery,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			return createIndex(ctx, mongoDatabase, webhookDelivery, "status_createdAt", bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}, false)
+		},
+	},
+	{
+		Version:     5,
+		Description: "index on outbox_message.status",
+		Collection:  outboxMessage,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			return createIndex(ctx, mongoDatabase, outboxMessage, "status_id", bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}, false)
+		},
+	},
+	{
+		Version:     6,
+		Description: "backfill conversation_mapping.orphaned",
+		Collection:  conversationMapping,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			query := map[string]interface{}{
+				"orphaned": map[string]interface{}{"$exists": false},
+			}
+			update := map[string]interface{}{
+				"$set": map[string]interface{}{"orphaned": false},
+			}
+
+			_, err := mongoDatabase.UpdateMany(ctx, conversationMapping, query, update)
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "index on webhook_delivery.nextAttemptAt",
+		Collection:  webhookDelivery,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			return createIndex(ctx, mongoDatabase, webhookDelivery, "status_nextAttemptAt", bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}, false)
+		},
+	},
+	{
+		Version:     8,
+		Description: "index on outbox_message.sentAt",
+		Collection:  outboxMessage,
+		Up: func(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+			return createIndex(ctx, mongoDatabase, outboxMessage, "status_sentAt", bson.D{{Key: "status", Value: 1}, {Key: "sentAt", Value: 1}}, false)
+		},
+	},
+}
+
+func NewSchemaMigrationRepository(storageConfig configs.StorageConfig, mongoDatabase library.MongoDatabase) SchemaMigrationRepository {
+	return &SchemaMigrationRepositoryImpl{
+		StorageConfig: storageConfig,
+		MongoDatabase: mongoDatabase,
+	}
+}
+
+func (s *SchemaMigrationRepositoryImpl) Migrations() []Migration {
+	if s.StorageConfig.MappingBackend == configs.StorageBackendMongo {
+		return migrations
+	}
+
+	results := []Migration{}
+	for _, migration := range migrations {
+		if migration.Collection != conversationMapping {
+			results = append(results, migration)
+		}
+	}
+
+	return results
+}
+
+func (s *SchemaMigrationRepositoryImpl) FindApplied(ctx context.Context) ([]model.SchemaMigration, error) {
+	query := map[string]interface{}{}
+
+	cursor, err := s.MongoDatabase.Find(ctx, schemaMigration, query, options.Find().SetSort(map[string]interface{}{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []model.SchemaMigration{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *SchemaMigrationRepositoryImpl) Apply(ctx context.Context, migration Migration) error {
+	if err := migration.Up(ctx, s.MongoDatabase); err != nil {
+		return err
+	}
+
+	query := map[string]interface{}{
+		"_id": migration.Version,
+	}
+
+	_, err := s.MongoDatabase.ReplaceOne(ctx, schemaMigration, query, model.SchemaMigration{
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppliedAt:   time.Now(),
+	}, options.Replace().SetUpsert(true))
+
+	return err
+}
+
+func createIndex(ctx context.Context, mongoDatabase library.MongoDatabase, collection string, name string, keys bson.D, unique bool) error {
+	index := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(name).SetUnique(unique),
+	}
+
+	if _, err := mongoDatabase.CreateIndex(ctx, collection, index); err != nil {
+		return fmt.Errorf("failed to create index %s on %s: %w", name, collection, err)
+	}
+
+	return nil
+}
+
+func deleteDuplicatePartitions(ctx context.Context, mongoDatabase library.MongoDatabase) error {
+	query := map[string]interface{}{}
+	sort := bson.D{{Key: "partition", Value: 1}, {Key: "_id", Value: -1}}
+
+	cursor, err := mongoDatabase.Find(ctx, conversationMapping, query, options.Find().SetSort(sort).SetProjection(map[string]interface{}{"partition": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	kept := map[int]bson.ObjectID{}
+	duplicates := []bson.ObjectID{}
+	for cursor.Next(ctx) {
+		var mapping model.ConversationMapping
+		if err := cursor.Decode(&mapping); err != nil {
+			return err
+		}
+
+		if keptId, ok := kept[mapping.Partition]; ok {
+			slog.WarnContext(ctx, "Deleting duplicate conversation mapping",
+				slog.String("id", mapping.Id.Hex()),
+				slog.Int("partition", mapping.Partition),
+				slog.String("keptId", keptId.Hex()),
+			)
+			duplicates = append(duplicates, mapping.Id)
+			continue
+		}
+		kept[mapping.Partition] = mapping.Id
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	deleted, err := mongoDatabase.DeleteMany(ctx, conversationMapping, map[string]interface{}{
+		"_id": map[string]interface{}{"$in": duplicates},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete duplicate conversation mappings: %w", err)
+	}
+
+	slog.WarnContext(ctx, "Deleted duplicate conversation mappings", slog.Int64("deleted", deleted.DeletedCount))
+
+	return nil
+}
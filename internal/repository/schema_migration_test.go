@@ -0,0 +1,22 @@
+package repository_test
+
+import (
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/repository"
+	"testing"
+)
+
+func TestSchemaMigrationsSkipMappingsOutsideMongo(t *testing.T) {
+	all := repository.NewSchemaMigrationRepository(configs.StorageConfig{MappingBackend: configs.StorageBackendMongo}, library.NewMemoryMongoDatabase()).Migrations()
+	sqlite := repository.NewSchemaMigrationRepository(configs.StorageConfig{MappingBackend: configs.StorageBackendSqlite}, library.NewMemoryMongoDatabase()).Migrations()
+
+	if len(sqlite) == 0 || len(sqlite) >= len(all) {
+		t.Fatalf("got %d migrations for sqlite mappings and %d for mongo mappings", len(sqlite), len(all))
+	}
+	for _, migration := range sqlite {
+		if migration.Collection == "conversation_mapping" {
+			t.Fatalf("migration %d targets conversation_mapping although mappings are not stored in mongo", migration.Version)
+		}
+	}
+}
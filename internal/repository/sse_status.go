@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+)
+
+const (
+	sseStatus = "sse_status"
+)
+
+type (
+	SseStatusRepository interface {
+		FindAll(ctx context.Context) ([]model.SseStatus, error)
+		Upsert(ctx context.Context, data model.SseStatus) (*mongo.UpdateResult, error)
+	}
+
+	SseStatusRepositoryImpl struct {
+		MongoDatabase library.MongoDatabase
+	}
+)
+
+func NewSseStatusRepository(mongoDatabase library.MongoDatabase) SseStatusRepository {
+	return &SseStatusRepositoryImpl{
+		MongoDatabase: mongoDatabase,
+	}
+}
+
+func (s *SseStatusRepositoryImpl) FindAll(ctx context.Context) ([]model.SseStatus, error) {
+	query := map[string]interface{}{}
+
+	cursor, err := s.MongoDatabase.Find(ctx, sseStatus, query)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []model.SseStatus{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *SseStatusRepositoryImpl) Upsert(ctx context.Context, data model.SseStatus) (*mongo.UpdateResult, error) {
+	query := map[string]interface{}{
+		"_id": data.Partition,
+	}
+
+	return s.MongoDatabase.ReplaceOne(ctx, sseStatus, query, data, options.Replace().SetUpsert(true))
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"time"
+)
+
+const (
+	conversationCallback = "conversation_callback"
+	webhookDelivery      = "webhook_delivery"
+)
+
+type (
+	ConversationCallbackRepository interface {
+		FindOneByConversationId(ctx context.Context, conversationId string) (*model.ConversationCallback, error)
+		Upsert(ctx context.Context, data model.ConversationCallback) (*mongo.UpdateResult, error)
+	}
+
+	ConversationCallbackRepositoryImpl struct {
+		MongoDatabase library.MongoDatabase
+	}
+
+	WebhookDeliveryRepository interface {
+		FindAll(ctx context.Context, status string) ([]model.WebhookDelivery, error)
+		FindOneById(ctx context.Context, id bson.ObjectID) (*model.WebhookDelivery, error)
+		Upsert(ctx context.Context, data model.WebhookDelivery) (*mongo.UpdateResult, error)
+		ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time) (*model.WebhookDelivery, error)
+	}
+
+	WebhookDeliveryRepositoryImpl struct {
+		MongoDatabase library.MongoDatabase
+	}
+)
+
+func NewConversationCallbackRepository(mongoDatabase library.MongoDatabase) ConversationCallbackRepository {
+	return &ConversationCallbackRepositoryImpl{
+		MongoDatabase: mongoDatabase,
+	}
+}
+
+func (s *ConversationCallbackRepositoryImpl) FindOneByConversationId(ctx context.Context, conversationId string) (*model.ConversationCallback, error) {
+	query := map[string]interface{}{
+		"conversationId": conversationId,
+	}
+
+	singleResult := s.MongoDatabase.FindOne(ctx, conversationCallback, query)
+	if err := singleResult.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result model.ConversationCallback
+	if err := singleResult.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *ConversationCallbackRepositoryImpl) Upsert(ctx context.Context, data model.ConversationCallback) (*mongo.UpdateResult, error) {
+	query := map[string]interface{}{
+		"conversationId": data.ConversationId,
+	}
+
+	return s.MongoDatabase.ReplaceOne(ctx, conversationCallback, query, data, options.Replace().SetUpsert(true))
+}
+
+func NewWebhookDeliveryRepository(mongoDatabase library.MongoDatabase) WebhookDeliveryRepository {
+	return &WebhookDeliveryRepositoryImpl{
+		MongoDatabase: mongoDatabase,
+	}
+}
+
+func (s *WebhookDeliveryRepositoryImpl) FindAll(ctx context.Context, status string) ([]model.WebhookDelivery, error) {
+	query := map[string]interface{}{}
+	if status != "" {
+		query["status"] = status
+	}
+
+	cursor, err := s.MongoDatabase.Find(ctx, webhookDelivery, query)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []model.WebhookDelivery{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *WebhookDeliveryRepositoryImpl) FindOneById(ctx context.Context, id bson.ObjectID) (*model.WebhookDelivery, error) {
+	query := map[string]interface{}{
+		"_id": id,
+	}
+
+	singleResult := s.MongoDatabase.FindOne(ctx, webhookDelivery, query)
+	if err := singleResult.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result model.WebhookDelivery
+	if err := singleResult.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *WebhookDeliveryRepositoryImpl) Upsert(ctx context.Context, data model.WebhookDelivery) (*mongo.UpdateResult, error) {
+	query := map[string]interface{}{
+		"_id": data.Id,
+	}
+
+	return s.MongoDatabase.ReplaceOne(ctx, webhookDelivery, query, data, options.Replace().SetUpsert(true))
+}
+
+func (s *WebhookDeliveryRepositoryImpl) ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time) (*model.WebhookDelivery, error) {
+	query := map[string]interface{}{
+		"status": model.WebhookStatusPending,
+		"$or": []interface{}{
+			map[string]interface{}{"nextAttemptAt": map[string]interface{}{"$lte": now}},
+			map[string]interface{}{"nextAttemptAt": map[string]interface{}{"$exists": false}},
+		},
+	}
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{"nextAttemptAt": leaseUntil},
+	}
+
+	singleResult := s.MongoDatabase.FindOneAndUpdate(ctx, webhookDelivery, query, update, options.FindOneAndUpdate().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).SetReturnDocument(options.After))
+	if err := singleResult.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var result model.WebhookDelivery
+	if err := singleResult.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
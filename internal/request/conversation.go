@@ -1,20 +1,21 @@
 package request
 
 type CreateConversationRequest struct {
-	ConversationId    string                              `json:"conversationId" validate:"required"`
-	EsDeveloperName   string                              `json:"esDeveloperName" validate:"required"`
-	Language          string                              `json:"language" validate:"required"`
-	RoutingAttributes CreateConversationRoutingAttributes `json:"routingAttributes" validate:"required"`
+	ConversationId    string                              `json:"conversationId" protobuf:"1" validate:"required"`
+	EsDeveloperName   string                              `json:"esDeveloperName" protobuf:"2" validate:"required"`
+	Language          string                              `json:"language" protobuf:"3" validate:"required"`
+	RoutingAttributes CreateConversationRoutingAttributes `json:"routingAttributes" protobuf:"4" validate:"required"`
+	CallbackUrl       string                              `json:"callbackUrl,omitempty" protobuf:"5" validate:"omitempty,url"`
 }
 
 type CreateConversationRoutingAttributes struct {
-	CaseId        string `json:"caseId" validate:"required"`
-	AccountId     string `json:"accountId" validate:"required"`
-	CustomerName  string `json:"customerName" validate:"required"`
-	CustomerPhone string `json:"customerPhone" validate:"required"`
-	CustomerEmail string `json:"customerEmail" validate:"required"`
-	Origin        string `json:"origin" validate:"required"`
-	SourceType    string `json:"sourceType" validate:"required"`
+	CaseId        string `json:"caseId" protobuf:"1" validate:"required"`
+	AccountId     string `json:"accountId" protobuf:"2" validate:"required"`
+	CustomerName  string `json:"customerName" protobuf:"3" validate:"required" sensitive:"true"`
+	CustomerPhone string `json:"customerPhone" protobuf:"4" validate:"required" sensitive:"true"`
+	CustomerEmail string `json:"customerEmail" protobuf:"5" validate:"required" sensitive:"true"`
+	Origin        string `json:"origin" protobuf:"6" validate:"required"`
+	SourceType    string `json:"sourceType" protobuf:"7" validate:"required"`
 }
 
 type GenerateTokenRequest struct {
@@ -0,0 +1,57 @@
+package request
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EnvelopeVersion = 1
+
+	CommandCreateConversation = "conversation.create"
+	CommandSendMessage        = "conversation.send-message"
+	CommandCloseConversation  = "conversation.close"
+	CommandTyping             = "conversation.typing"
+	CommandAttachment         = "conversation.attachment"
+	CommandResubscribe        = "partition.resubscribe"
+)
+
+type Envelope struct {
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	Id        string          `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+type SendMessageRequest struct {
+	ConversationId  string `json:"conversationId" protobuf:"1" validate:"required"`
+	EsDeveloperName string `json:"esDeveloperName" protobuf:"2" validate:"required"`
+	MessageId       string `json:"messageId" protobuf:"3" validate:"required"`
+	Text            string `json:"text" protobuf:"4" validate:"required"`
+}
+
+type CloseConversationRequest struct {
+	ConversationId  string `json:"conversationId" protobuf:"1" validate:"required"`
+	EsDeveloperName string `json:"esDeveloperName" protobuf:"2" validate:"required"`
+}
+
+type TypingRequest struct {
+	ConversationId string `json:"conversationId" protobuf:"1" validate:"required"`
+	EntryId        string `json:"entryId" protobuf:"2" validate:"required"`
+	Started        bool   `json:"started" protobuf:"3"`
+}
+
+type AttachmentRequest struct {
+	ConversationId  string `json:"conversationId" protobuf:"1" validate:"required"`
+	EsDeveloperName string `json:"esDeveloperName" protobuf:"2" validate:"required"`
+	MessageId       string `json:"messageId" protobuf:"3" validate:"required"`
+	FileName        string `json:"fileName" protobuf:"4" validate:"required"`
+	ContentType     string `json:"contentType" protobuf:"5" validate:"required"`
+	Data            []byte `json:"data" protobuf:"6" validate:"required"`
+	Text            string `json:"text,omitempty" protobuf:"7"`
+}
+
+type ResubscribeRequest struct {
+	Partition int `json:"partition" protobuf:"1" validate:"min=0"`
+}
@@ -0,0 +1,19 @@
+package response
+
+import "time"
+
+type ConversationMappingResponse struct {
+	Partition    int        `json:"partition"`
+	Token        string     `json:"token"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	Expired      bool       `json:"expired"`
+	Orphaned     bool       `json:"orphaned"`
+	SseStatus    string     `json:"sseStatus"`
+	SseOwner     string     `json:"sseOwner,omitempty"`
+	SseError     string     `json:"sseError,omitempty"`
+	SseUpdatedAt *time.Time `json:"sseUpdatedAt,omitempty"`
+}
+
+type DeleteOrphanedResponse struct {
+	Deleted int64 `json:"deleted"`
+}
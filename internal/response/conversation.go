@@ -1,6 +1,43 @@
 package response
 
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ConversationStatusCreated = "created"
+	ConversationStatusFailed  = "failed"
+)
+
 type GenerateTokenResponse struct {
 	AccessToken string `json:"accessToken"`
 	LastEventId string `json:"lastEventId"`
 }
+
+type ConversationReply struct {
+	CorrelationId  string          `json:"correlationId"`
+	ConversationId string          `json:"conversationId"`
+	Status         string          `json:"status"`
+	Body           json.RawMessage `json:"body,omitempty"`
+	Error          *ErrorResponse  `json:"error,omitempty"`
+}
+
+type QueuedConversationResponse struct {
+	Message       string `json:"message"`
+	RequestId     string `json:"requestId"`
+	CorrelationId string `json:"correlationId,omitempty"`
+}
+
+type ConversationEvent struct {
+	Type           string          `json:"type"`
+	RequestId      string          `json:"requestId"`
+	ConversationId string          `json:"conversationId"`
+	Partition      int             `json:"partition"`
+	Offset         int64           `json:"offset"`
+	Response       json.RawMessage `json:"response,omitempty"`
+	Error          *ErrorResponse  `json:"error,omitempty"`
+	Attempts       int             `json:"attempts"`
+	LatencyMs      int64           `json:"latencyMs"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
@@ -0,0 +1,6 @@
+package response
+
+type ErrorResponse struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
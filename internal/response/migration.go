@@ -0,0 +1,10 @@
+package response
+
+import "time"
+
+type MigrationResponse struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
@@ -0,0 +1,14 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookPayload struct {
+	Id             string          `json:"id"`
+	Event          string          `json:"event"`
+	ConversationId string          `json:"conversationId"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
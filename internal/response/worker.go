@@ -0,0 +1,21 @@
+package response
+
+import "time"
+
+type WorkerStatusResponse struct {
+	Hostname   string                    `json:"hostname"`
+	Generation int32                     `json:"generation"`
+	Partitions []WorkerPartitionResponse `json:"partitions"`
+}
+
+type WorkerPartitionResponse struct {
+	Topic          string     `json:"topic"`
+	Partition      int32      `json:"partition"`
+	InFlight       int64      `json:"inFlight"`
+	LastOffset     int64      `json:"lastOffset"`
+	Paused         bool       `json:"paused"`
+	SseStatus      string     `json:"sseStatus"`
+	SseError       string     `json:"sseError,omitempty"`
+	SseConnectedAt *time.Time `json:"sseConnectedAt,omitempty"`
+	SseLastEventAt *time.Time `json:"sseLastEventAt,omitempty"`
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/response"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sseStatusUnknown = "unknown"
+)
+
+type (
+	AdminService interface {
+		FindMappings(ctx context.Context) ([]response.ConversationMappingResponse, error)
+		RegenerateToken(ctx context.Context, partition int) error
+		DeleteOrphaned(ctx context.Context) (response.DeleteOrphanedResponse, error)
+		Resubscribe(ctx context.Context, partition int) error
+	}
+
+	AdminServiceImpl struct {
+		conversationService           ConversationService
+		conversationMappingRepository repository.ConversationMappingRepository
+		sseStatusRepository           repository.SseStatusRepository
+	}
+)
+
+func NewAdminService(conversationService ConversationService, conversationMappingRepository repository.ConversationMappingRepository, sseStatusRepository repository.SseStatusRepository) AdminService {
+	return &AdminServiceImpl{
+		conversationService:           conversationService,
+		conversationMappingRepository: conversationMappingRepository,
+		sseStatusRepository:           sseStatusRepository,
+	}
+}
+
+func (a *AdminServiceImpl) FindMappings(ctx context.Context) ([]response.ConversationMappingResponse, error) {
+	mappings, err := a.conversationMappingRepository.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find conversation mappings: %w", err)
+	}
+
+	statuses, err := a.sseStatusRepository.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find SSE statuses: %w", err)
+	}
+
+	results := make([]response.ConversationMappingResponse, 0, len(mappings))
+	for _, mapping := range mappings {
+		result := response.ConversationMappingResponse{
+			Partition: mapping.Partition,
+			Token:     maskToken(mapping.Token),
+			ExpiresAt: tokenExpiry(mapping.Token),
+			Orphaned:  mapping.Orphaned,
+			SseStatus: sseStatusUnknown,
+		}
+		if result.ExpiresAt != nil {
+			result.Expired = result.ExpiresAt.Before(time.Now())
+		}
+
+		for _, status := range statuses {
+			if status.Partition == mapping.Partition {
+				updatedAt := status.UpdatedAt
+				result.SseStatus = status.Status
+				result.SseOwner = status.Owner
+				result.SseError = status.Error
+				result.SseUpdatedAt = &updatedAt
+				break
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Partition < results[j].Partition
+	})
+
+	return results, nil
+}
+
+func (a *AdminServiceImpl) RegenerateToken(ctx context.Context, partition int) error {
+	return a.conversationService.RegenerateToken(ctx, partition)
+}
+
+func (a *AdminServiceImpl) DeleteOrphaned(ctx context.Context) (response.DeleteOrphanedResponse, error) {
+	deleted, err := a.conversationMappingRepository.DeleteOrphaned(ctx)
+	if err != nil {
+		return response.DeleteOrphanedResponse{}, fmt.Errorf("failed to delete orphaned mappings: %w", err)
+	}
+
+	return response.DeleteOrphanedResponse{Deleted: deleted}, nil
+}
+
+func (a *AdminServiceImpl) Resubscribe(ctx context.Context, partition int) error {
+	return a.conversationService.ProducePartitionCommand(ctx, request.CommandResubscribe, partition, request.ResubscribeRequest{Partition: partition})
+}
+
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return strings.Repeat("*", len(token))
+	}
+
+	return token[:4] + strings.Repeat("*", 8) + token[len(token)-4:]
+}
+
+func tokenExpiry(token string) *time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return nil
+	}
+
+	expiresAt := time.Unix(claims.Exp, 0)
+
+	return &expiresAt
+}
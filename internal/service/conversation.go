@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/IBM/sarama"
 	"log/slog"
 	"salesforce-sse-worker/configs"
 	"salesforce-sse-worker/internal/library"
@@ -13,91 +14,503 @@ import (
 	"salesforce-sse-worker/internal/request"
 	"salesforce-sse-worker/internal/response"
 	"salesforce-sse-worker/internal/service/outbound"
+	"time"
+)
+
+const (
+	sseEventRoutingResult     = "CONVERSATION_ROUTING_RESULT"
+	sseEventCloseConversation = "CONVERSATION_CLOSE_CONVERSATION"
+
+	partitionOwnerPollInterval = time.Second
 )
 
 type (
 	ConversationService interface {
 		GenerateToken(ctx context.Context, req request.GenerateTokenRequest) (string, error)
+		RegenerateToken(ctx context.Context, partition int) error
 		CreateConversationProducer(ctx context.Context, req request.CreateConversationRequest) (string, error)
-		CreateConversationConsumer(ctx context.Context, req request.CreateConversationRequest, partition int) error
+		CreateConversationAndWait(ctx context.Context, req request.CreateConversationRequest, wait time.Duration) (*response.ConversationReply, response.QueuedConversationResponse, error)
+		ProduceCommand(ctx context.Context, commandType string, conversationId string, payload interface{}) error
+		ProducePartitionCommand(ctx context.Context, commandType string, partition int, payload interface{}) error
+		CreateConversationConsumer(ctx context.Context, req request.CreateConversationRequest, partition int) (ConsumeResult, error)
+		HandleEvent(ctx context.Context, eventType string, data []byte)
+		NewConversationReply(correlationId string, req request.CreateConversationRequest, body []byte, err error) response.ConversationReply
+		NewConversationEvent(requestId string, req request.CreateConversationRequest, partition int, offset int64, result ConsumeResult, err error) response.ConversationEvent
+		SyncPartitions(ctx context.Context) error
+		WatchPartitions(ctx context.Context, isOwner func() bool)
+	}
+
+	ConsumeResult struct {
+		Body     []byte
+		Attempts int
+		Latency  time.Duration
 	}
 
 	ConversationServiceImpl struct {
-		kafkaConfig                   configs.KafkaConfig
-		kafkaProducer                 library.KafkaProducer
+		queueConfig                   configs.QueueConfig
+		workerConfig                  configs.WorkerConfig
+		salesforceConfig              configs.SalesforceConfig
+		queueAdmin                    library.QueueAdmin
+		queueProducer                 library.QueueProducer
+		queueReplyListener            library.QueueReplyListener
+		serializer                    library.Serializer
+		fieldCipher                   library.FieldCipher
+		tokenCache                    TokenCache
+		webhookService                WebhookService
+		outboxService                 OutboxService
 		salesforceOutbound            outbound.SalesforceOutbound
 		conversationMappingRepository repository.ConversationMappingRepository
 	}
 )
 
-func NewConversationService(kafkaConfig configs.KafkaConfig, kafkaProducer library.KafkaProducer, salesforceOutbound outbound.SalesforceOutbound, conversationMappingRepository repository.ConversationMappingRepository) ConversationService {
+func NewConversationService(queueConfig configs.QueueConfig, workerConfig configs.WorkerConfig, salesforceConfig configs.SalesforceConfig, queueAdmin library.QueueAdmin, queueProducer library.QueueProducer, queueReplyListener library.QueueReplyListener, serializer library.Serializer, fieldCipher library.FieldCipher, tokenCache TokenCache, webhookService WebhookService, outboxService OutboxService, salesforceOutbound outbound.SalesforceOutbound, conversationMappingRepository repository.ConversationMappingRepository) ConversationService {
 	return &ConversationServiceImpl{
-		kafkaConfig:                   kafkaConfig,
-		kafkaProducer:                 kafkaProducer,
+		queueConfig:                   queueConfig,
+		workerConfig:                  workerConfig,
+		salesforceConfig:              salesforceConfig,
+		queueAdmin:                    queueAdmin,
+		queueProducer:                 queueProducer,
+		queueReplyListener:            queueReplyListener,
+		serializer:                    serializer,
+		fieldCipher:                   fieldCipher,
+		tokenCache:                    tokenCache,
+		webhookService:                webhookService,
+		outboxService:                 outboxService,
 		salesforceOutbound:            salesforceOutbound,
 		conversationMappingRepository: conversationMappingRepository,
 	}
 }
 
 func (m *ConversationServiceImpl) GenerateToken(ctx context.Context, req request.GenerateTokenRequest) (string, error) {
-	for partition := 0; partition <= m.kafkaConfig.PartitionCount; partition++ {
-		resp, err := m.salesforceOutbound.GenerateToken(ctx, req)
-		if err != nil {
-			continue
+	partitionCount, err := m.queueAdmin.PartitionCount(ctx, m.queueConfig.Topics[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to discover partition count: %w", err)
+	}
+
+	for partition := 0; partition < partitionCount; partition++ {
+		if err := m.generateToken(ctx, req, partition); err != nil {
+			slog.ErrorContext(ctx, "Failed to generate token", slog.Int("partition", partition), slog.Any("error", err))
 		}
+	}
 
-		var data response.GenerateTokenResponse
-		if err := json.Unmarshal(resp, &data); err != nil {
-			continue
+	return "Successfully generated tokens", nil
+}
+
+func (m *ConversationServiceImpl) RegenerateToken(ctx context.Context, partition int) error {
+	partitionCount, err := m.queueAdmin.PartitionCount(ctx, m.queueConfig.Topics[0])
+	if err != nil {
+		return fmt.Errorf("failed to discover partition count: %w", err)
+	}
+
+	if partition < 0 || partition >= partitionCount {
+		return fmt.Errorf("%w: %d", ErrPartitionNotFound, partition)
+	}
+
+	if err := m.generateToken(ctx, m.defaultGenerateTokenRequest(), partition); err != nil {
+		return err
+	}
+
+	if err := m.ProducePartitionCommand(ctx, request.CommandResubscribe, partition, request.ResubscribeRequest{Partition: partition}); err != nil {
+		return fmt.Errorf("failed to queue resubscribe after regenerating token: %w", err)
+	}
+
+	return nil
+}
+
+func (m *ConversationServiceImpl) CreateConversationProducer(ctx context.Context, req request.CreateConversationRequest) (string, error) {
+	return m.produceCreateConversation(ctx, req, nil)
+}
+
+func (m *ConversationServiceImpl) CreateConversationAndWait(ctx context.Context, req request.CreateConversationRequest, wait time.Duration) (*response.ConversationReply, response.QueuedConversationResponse, error) {
+	queued := response.QueuedConversationResponse{Message: "Message successfully queued", CorrelationId: newId()}
+	if !m.queueReplyListener.Enabled() {
+		return nil, queued, ErrReplyListenerDisabled
+	}
+
+	correlationId := queued.CorrelationId
+
+	replies := m.queueReplyListener.Register(correlationId)
+	defer m.queueReplyListener.Unregister(correlationId)
+
+	headers := []library.MessageHeader{
+		{Key: library.HeaderCorrelationId, Value: []byte(correlationId)},
+		{Key: library.HeaderReplyTopic, Value: []byte(m.queueReplyListener.Topic())},
+	}
+	requestId, err := m.produceCreateConversation(ctx, req, headers)
+	if err != nil {
+		return nil, queued, err
+	}
+	queued.RequestId = requestId
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case message := <-replies:
+		var reply response.ConversationReply
+		if err := json.Unmarshal(message.Value, &reply); err != nil {
+			return nil, queued, fmt.Errorf("failed to decode reply: %w", err)
 		}
 
-		if _, err = m.conversationMappingRepository.Upsert(ctx, model.ConversationMapping{
-			Partition: partition,
-			Token:     data.AccessToken,
-		}); err != nil {
-			continue
+		return &reply, queued, nil
+	case <-timer.C:
+		return nil, queued, nil
+	case <-ctx.Done():
+		return nil, queued, ctx.Err()
+	}
+}
+
+func (m *ConversationServiceImpl) CreateConversationConsumer(ctx context.Context, req request.CreateConversationRequest, partition int) (ConsumeResult, error) {
+	if req.CallbackUrl != "" {
+		if err := m.webhookService.RegisterCallback(ctx, req.ConversationId, req.CallbackUrl); err != nil {
+			slog.ErrorContext(ctx, "Failed to register callback", slog.String("conversationId", req.ConversationId), slog.Any("error", err))
 		}
 	}
 
-	return "Successfully generated tokens", nil
+	result, err := m.createConversationWithRetry(ctx, req, partition)
+	if req.CallbackUrl == "" {
+		return result, err
+	}
+
+	if err != nil {
+		m.webhookService.Notify(ctx, req.ConversationId, model.ConversationEventFailed, NewErrorResponse(err))
+		return result, err
+	}
+
+	m.webhookService.Notify(ctx, req.ConversationId, model.ConversationEventCreated, rawBody(result.Body))
+
+	return result, nil
 }
 
-func (m *ConversationServiceImpl) CreateConversationProducer(ctx context.Context, req request.CreateConversationRequest) (string, error) {
-	payload, err := json.Marshal(req)
+func (m *ConversationServiceImpl) createConversationWithRetry(ctx context.Context, req request.CreateConversationRequest, partition int) (ConsumeResult, error) {
+	start := time.Now()
+	backoff := time.Duration(m.workerConfig.RetryBackoff) * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		body, err := m.createConversation(ctx, req, partition)
+
+		result := ConsumeResult{Body: body, Attempts: attempt, Latency: time.Since(start)}
+		if err == nil || attempt >= m.workerConfig.MaxAttempts || !IsTransientError(err) {
+			return result, err
+		}
+
+		slog.WarnContext(ctx, "Retrying conversation creation",
+			slog.String("conversationId", req.ConversationId),
+			slog.Int("attempt", attempt),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoff * time.Duration(attempt)):
+		}
+	}
+}
+
+func (m *ConversationServiceImpl) HandleEvent(ctx context.Context, eventType string, data []byte) {
+	var event struct {
+		ConversationId string `json:"conversationId"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil || event.ConversationId == "" {
+		return
+	}
+
+	switch eventType {
+	case sseEventRoutingResult:
+		m.webhookService.Notify(ctx, event.ConversationId, model.ConversationEventRouted, rawBody(data))
+	case sseEventCloseConversation:
+		m.webhookService.Notify(ctx, event.ConversationId, model.ConversationEventClosed, rawBody(data))
+	}
+}
+
+func (m *ConversationServiceImpl) createConversation(ctx context.Context, req request.CreateConversationRequest, partition int) ([]byte, error) {
+	token, err := m.tokenCache.Get(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := m.salesforceOutbound.CreateConversation(ctx, token, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation in Salesforce: %w", err)
+	}
+
+	return body, nil
+}
+
+func (m *ConversationServiceImpl) NewConversationReply(correlationId string, req request.CreateConversationRequest, body []byte, err error) response.ConversationReply {
+	reply := response.ConversationReply{
+		CorrelationId:  correlationId,
+		ConversationId: req.ConversationId,
+		Status:         response.ConversationStatusCreated,
+	}
+
+	if err != nil {
+		reply.Status = response.ConversationStatusFailed
+		reply.Error = NewErrorResponse(err)
+		return reply
+	}
+
+	reply.Body = rawBody(body)
+
+	return reply
+}
+
+func (m *ConversationServiceImpl) NewConversationEvent(requestId string, req request.CreateConversationRequest, partition int, offset int64, result ConsumeResult, err error) response.ConversationEvent {
+	event := response.ConversationEvent{
+		Type:           model.ConversationEventCreated,
+		RequestId:      requestId,
+		ConversationId: req.ConversationId,
+		Partition:      partition,
+		Offset:         offset,
+		Response:       rawBody(result.Body),
+		Attempts:       result.Attempts,
+		LatencyMs:      result.Latency.Milliseconds(),
+		Timestamp:      time.Now(),
+	}
+
+	if err != nil {
+		event.Type = model.ConversationEventFailed
+		event.Error = NewErrorResponse(err)
+	}
+
+	return event
+}
+
+func (m *ConversationServiceImpl) ProduceCommand(ctx context.Context, commandType string, conversationId string, payload interface{}) error {
+	_, err := m.produceCommand(ctx, commandType, conversationId, payload, nil)
+	return err
+}
+
+func (m *ConversationServiceImpl) produceCreateConversation(ctx context.Context, req request.CreateConversationRequest, headers []library.MessageHeader) (string, error) {
+	if err := m.fieldCipher.EncryptFields(&req); err != nil {
+		return "", fmt.Errorf("failed to encrypt req: %w", err)
+	}
+
+	return m.produceCommand(ctx, request.CommandCreateConversation, req.ConversationId, req, headers)
+}
+
+func (m *ConversationServiceImpl) produceCommand(ctx context.Context, commandType string, conversationId string, payload interface{}, headers []library.MessageHeader) (string, error) {
+	msg, err := m.newCommandMessage(ctx, commandType, payload, headers)
+	if err != nil {
+		return "", err
+	}
+	msg.Key = []byte(conversationId)
+
+	if err := m.produce(ctx, commandType, msg); err != nil {
+		return "", err
+	}
+
+	return library.GetHeader(msg, library.HeaderRequestId), nil
+}
+
+func (m *ConversationServiceImpl) ProducePartitionCommand(ctx context.Context, commandType string, partition int, payload interface{}) error {
+	partitionCount, err := m.queueAdmin.PartitionCount(ctx, m.queueConfig.Topics[0])
+	if err != nil {
+		return fmt.Errorf("failed to discover partition count: %w", err)
+	}
+
+	if partition < 0 || partition >= partitionCount {
+		return fmt.Errorf("%w: %d", ErrPartitionNotFound, partition)
+	}
+
+	msg, err := m.newCommandMessage(ctx, commandType, payload, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal req: %w", err)
+		return err
 	}
+	msg.Partition = int32(partition)
+	msg.ManualPartition = true
+
+	return m.produce(ctx, commandType, msg)
+}
 
-	msg := &sarama.ProducerMessage{
-		Topic: m.kafkaConfig.Topics[0],
-		Value: sarama.ByteEncoder(payload),
+func (m *ConversationServiceImpl) newCommandMessage(ctx context.Context, commandType string, payload interface{}, headers []library.MessageHeader) (*library.Message, error) {
+	encodedPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal req: %w", err)
 	}
 
-	partition, offset, err := m.kafkaProducer.Produce(ctx, msg)
+	requestId := newId()
+	envelope, err := m.serializer.Serialize(ctx, m.queueConfig.Topics[0], request.Envelope{
+		Type:      commandType,
+		Version:   request.EnvelopeVersion,
+		Id:        requestId,
+		Timestamp: time.Now(),
+		Payload:   encodedPayload,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to produce Kafka message: %w", err)
+		return nil, fmt.Errorf("failed to serialize envelope: %w", err)
+	}
+
+	return &library.Message{
+		Topic: m.queueConfig.Topics[0],
+		Value: envelope,
+		Headers: append(headers, library.MessageHeader{
+			Key:   library.HeaderRequestId,
+			Value: []byte(requestId),
+		}),
+	}, nil
+}
+
+func (m *ConversationServiceImpl) produce(ctx context.Context, commandType string, msg *library.Message) error {
+	if m.outboxService.Enabled() {
+		if err := m.outboxService.Enqueue(ctx, msg); err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "Queue message written to outbox",
+			slog.String("type", commandType),
+			slog.String("conversationId", string(msg.Key)),
+			slog.String("topic", msg.Topic),
+		)
+
+		return nil
 	}
 
-	slog.InfoContext(ctx, "Kafka message produced",
-		slog.String("conversationId", req.ConversationId),
+	partition, offset, err := m.queueProducer.Produce(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to produce queue message: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Queue message produced",
+		slog.String("type", commandType),
+		slog.String("conversationId", string(msg.Key)),
 		slog.String("topic", msg.Topic),
 		slog.Int("partition", int(partition)),
 		slog.Int64("offset", offset),
 	)
 
-	return "Message successfully queued", nil
+	return nil
+}
+
+func (m *ConversationServiceImpl) SyncPartitions(ctx context.Context) error {
+	partitionCount, err := m.queueAdmin.PartitionCount(ctx, m.queueConfig.Topics[0])
+	if err != nil {
+		return fmt.Errorf("failed to discover partition count: %w", err)
+	}
+
+	conversationMappings, err := m.conversationMappingRepository.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find conversation mappings: %w", err)
+	}
+
+	known := make(map[int]bool, len(conversationMappings))
+	for _, conversationMapping := range conversationMappings {
+		known[conversationMapping.Partition] = true
+
+		orphaned := conversationMapping.Partition >= partitionCount
+		if conversationMapping.Orphaned == orphaned {
+			continue
+		}
+
+		conversationMapping.Orphaned = orphaned
+		if _, err := m.conversationMappingRepository.Upsert(ctx, conversationMapping); err != nil {
+			return fmt.Errorf("failed to flag partition %d: %w", conversationMapping.Partition, err)
+		}
+
+		slog.InfoContext(ctx, "Conversation mapping flagged",
+			slog.Int("partition", conversationMapping.Partition),
+			slog.Bool("orphaned", orphaned),
+		)
+	}
+
+	for partition := 0; partition < partitionCount; partition++ {
+		if known[partition] {
+			continue
+		}
+
+		if err := m.generateToken(ctx, m.defaultGenerateTokenRequest(), partition); err != nil {
+			return fmt.Errorf("failed to generate token for partition %d: %w", partition, err)
+		}
+
+		slog.InfoContext(ctx, "Token generated for new partition", slog.Int("partition", partition))
+	}
+
+	return nil
 }
 
-func (m *ConversationServiceImpl) CreateConversationConsumer(ctx context.Context, req request.CreateConversationRequest, partition int) error {
-	conversationMapping, err := m.conversationMappingRepository.FindOneByPartition(ctx, partition)
-	if conversationMapping == nil || err != nil {
-		return fmt.Errorf("failed to find token for partition %d: %w", partition, err)
+func (m *ConversationServiceImpl) WatchPartitions(ctx context.Context, isOwner func() bool) {
+	interval := time.Duration(m.queueConfig.PartitionRefreshInterval) * time.Millisecond
+
+	lastCount := -1
+	for {
+		wait := interval
+		if !isOwner() {
+			lastCount = -1
+			wait = min(interval, partitionOwnerPollInterval)
+		} else if partitionCount, err := m.queueAdmin.PartitionCount(ctx, m.queueConfig.Topics[0]); err != nil {
+			slog.ErrorContext(ctx, "Failed to discover partition count", slog.Any("error", err))
+		} else if partitionCount != lastCount {
+			slog.InfoContext(ctx, "Partition count changed", slog.Int("previous", lastCount), slog.Int("current", partitionCount))
+
+			if err := m.SyncPartitions(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to sync partitions", slog.Any("error", err))
+			} else {
+				lastCount = partitionCount
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 	}
+}
 
-	_, err = m.salesforceOutbound.CreateConversation(ctx, conversationMapping.Token, req)
+func (m *ConversationServiceImpl) generateToken(ctx context.Context, req request.GenerateTokenRequest, partition int) error {
+	resp, err := m.salesforceOutbound.GenerateToken(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to create conversation in Salesforce: %w", err)
+		return err
 	}
 
+	var data response.GenerateTokenResponse
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if _, err = m.conversationMappingRepository.Upsert(ctx, model.ConversationMapping{
+		Partition: partition,
+		Token:     data.AccessToken,
+	}); err != nil {
+		return err
+	}
+
+	m.tokenCache.Invalidate(partition)
+
 	return nil
 }
+
+func (m *ConversationServiceImpl) defaultGenerateTokenRequest() request.GenerateTokenRequest {
+	return request.GenerateTokenRequest{
+		OrgId:               m.salesforceConfig.OrgId,
+		EsDeveloperName:     m.salesforceConfig.EsDeveloperName,
+		CapabilitiesVersion: m.salesforceConfig.CapabilitiesVersion,
+		Platform:            m.salesforceConfig.Platform,
+		Context: request.GenerateTokenContext{
+			AppName:       m.salesforceConfig.AppName,
+			ClientVersion: m.salesforceConfig.ClientVersion,
+		},
+	}
+}
+
+func newId() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+func rawBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if json.Valid(body) {
+		return body
+	}
+
+	encoded, _ := json.Marshal(string(body))
+
+	return encoded
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"salesforce-sse-worker/internal/response"
+	"salesforce-sse-worker/internal/service/outbound"
+)
+
+const (
+	ErrorTypeTokenNotFound  = "token_not_found"
+	ErrorTypeSalesforce     = "salesforce_error"
+	ErrorTypeInvalidMessage = "invalid_message"
+	ErrorTypeInternal       = "internal_error"
+)
+
+var (
+	ErrTokenNotFound  = errors.New("token not found")
+	ErrInvalidMessage = errors.New("invalid message")
+
+	ErrPartitionNotFound = errors.New("partition not found")
+
+	ErrReplyListenerDisabled = errors.New("reply listener is disabled")
+	ErrCallbackNotAllowed    = errors.New("callback url not allowed")
+)
+
+func NewErrorResponse(err error) *response.ErrorResponse {
+	if err == nil {
+		return nil
+	}
+
+	var salesforceError *outbound.SalesforceError
+
+	errorType := ErrorTypeInternal
+	switch {
+	case errors.Is(err, ErrTokenNotFound):
+		errorType = ErrorTypeTokenNotFound
+	case errors.Is(err, ErrInvalidMessage):
+		errorType = ErrorTypeInvalidMessage
+	case errors.As(err, &salesforceError):
+		errorType = ErrorTypeSalesforce
+	}
+
+	return &response.ErrorResponse{Type: errorType, Message: err.Error()}
+}
+
+func IsTransientError(err error) bool {
+	var (
+		salesforceError *outbound.SalesforceError
+		netError        net.Error
+	)
+
+	switch {
+	case errors.As(err, &salesforceError):
+		return salesforceError.StatusCode >= http.StatusInternalServerError || salesforceError.StatusCode == http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.As(err, &netError):
+		return netError.Timeout()
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/service/outbound"
+)
+
+type (
+	MessagingService interface {
+		SendMessage(ctx context.Context, req request.SendMessageRequest, partition int) error
+		CloseConversation(ctx context.Context, req request.CloseConversationRequest, partition int) error
+		SendTyping(ctx context.Context, req request.TypingRequest, partition int) error
+		SendAttachment(ctx context.Context, req request.AttachmentRequest, partition int) error
+	}
+
+	MessagingServiceImpl struct {
+		tokenCache         TokenCache
+		salesforceOutbound outbound.SalesforceOutbound
+	}
+)
+
+func NewMessagingService(tokenCache TokenCache, salesforceOutbound outbound.SalesforceOutbound) MessagingService {
+	return &MessagingServiceImpl{
+		tokenCache:         tokenCache,
+		salesforceOutbound: salesforceOutbound,
+	}
+}
+
+func (m *MessagingServiceImpl) SendMessage(ctx context.Context, req request.SendMessageRequest, partition int) error {
+	token, err := m.tokenCache.Get(ctx, partition)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.salesforceOutbound.SendMessage(ctx, token, req); err != nil {
+		return fmt.Errorf("failed to send message in Salesforce: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MessagingServiceImpl) CloseConversation(ctx context.Context, req request.CloseConversationRequest, partition int) error {
+	token, err := m.tokenCache.Get(ctx, partition)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.salesforceOutbound.CloseConversation(ctx, token, req); err != nil {
+		return fmt.Errorf("failed to close conversation in Salesforce: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MessagingServiceImpl) SendTyping(ctx context.Context, req request.TypingRequest, partition int) error {
+	token, err := m.tokenCache.Get(ctx, partition)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.salesforceOutbound.SendTyping(ctx, token, req); err != nil {
+		return fmt.Errorf("failed to send typing indicator in Salesforce: %w", err)
+	}
+
+	return nil
+}
+
+func (m *MessagingServiceImpl) SendAttachment(ctx context.Context, req request.AttachmentRequest, partition int) error {
+	token, err := m.tokenCache.Get(ctx, partition)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.salesforceOutbound.SendAttachment(ctx, token, req); err != nil {
+		return fmt.Errorf("failed to send attachment in Salesforce: %w", err)
+	}
+
+	return nil
+}
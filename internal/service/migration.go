@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/response"
+	"time"
+)
+
+type (
+	MigrationService interface {
+		Migrate(ctx context.Context) ([]response.MigrationResponse, error)
+		Status(ctx context.Context) ([]response.MigrationResponse, error)
+	}
+
+	MigrationServiceImpl struct {
+		schemaMigrationRepository repository.SchemaMigrationRepository
+	}
+)
+
+func NewMigrationService(migrationConfig configs.MigrationConfig, schemaMigrationRepository repository.SchemaMigrationRepository, lifecycle library.Lifecycle) MigrationService {
+	s := &MigrationServiceImpl{
+		schemaMigrationRepository: schemaMigrationRepository,
+	}
+
+	if migrationConfig.OnStartup {
+		lifecycle.Append(library.LifecycleHook{
+			Name:  "migrations",
+			Order: library.LifecycleOrderMigration,
+			OnStart: func(ctx context.Context) error {
+				_, err := s.Migrate(ctx)
+				return err
+			},
+		})
+	}
+
+	return s
+}
+
+func (s *MigrationServiceImpl) Migrate(ctx context.Context) ([]response.MigrationResponse, error) {
+	statuses, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []response.MigrationResponse{}
+	for _, migration := range s.schemaMigrationRepository.Migrations() {
+		if statusOf(statuses, migration.Version).Applied {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Applying migration", slog.Int("version", migration.Version), slog.String("description", migration.Description))
+
+		if err := s.schemaMigrationRepository.Apply(ctx, migration); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+		}
+
+		appliedAt := time.Now()
+		applied = append(applied, response.MigrationResponse{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Applied:     true,
+			AppliedAt:   &appliedAt,
+		})
+	}
+
+	return applied, nil
+}
+
+func (s *MigrationServiceImpl) Status(ctx context.Context) ([]response.MigrationResponse, error) {
+	appliedMigrations, err := s.schemaMigrationRepository.FindApplied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	statuses := []response.MigrationResponse{}
+	for _, migration := range s.schemaMigrationRepository.Migrations() {
+		status := response.MigrationResponse{Version: migration.Version, Description: migration.Description}
+		for _, appliedMigration := range appliedMigrations {
+			if appliedMigration.Version == migration.Version {
+				appliedAt := appliedMigration.AppliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func statusOf(statuses []response.MigrationResponse, version int) response.MigrationResponse {
+	for _, status := range statuses {
+		if status.Version == version {
+			return status
+		}
+	}
+
+	return response.MigrationResponse{Version: version}
+}
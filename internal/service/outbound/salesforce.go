@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
 	"salesforce-sse-worker/configs"
 	"salesforce-sse-worker/internal/library"
 	"salesforce-sse-worker/internal/request"
@@ -13,14 +17,27 @@ import (
 const (
 	ssePath                = "/eventrouter/v1/sse"
 	createConversationPath = "/iamessage/api/v2/conversation"
+	conversationPath       = "/iamessage/api/v2/conversation/%s"
+	sendMessagePath        = "/iamessage/api/v2/conversation/%s/message"
+	conversationEntryPath  = "/iamessage/api/v2/conversation/%s/entry"
+	conversationFilePath   = "/iamessage/api/v2/conversation/%s/file"
 	generateTokenPath      = "/iamessage/api/v2/authorization/unauthenticated/access-token"
 )
 
 type (
+	SalesforceError struct {
+		StatusCode int
+		Body       []byte
+	}
+
 	SalesforceOutbound interface {
 		GenerateToken(ctx context.Context, req request.GenerateTokenRequest) ([]byte, error)
 		CreateConversation(ctx context.Context, token string, req request.CreateConversationRequest) ([]byte, error)
-		Subscribe(ctx context.Context, token string) error
+		SendMessage(ctx context.Context, token string, req request.SendMessageRequest) ([]byte, error)
+		CloseConversation(ctx context.Context, token string, req request.CloseConversationRequest) ([]byte, error)
+		SendTyping(ctx context.Context, token string, req request.TypingRequest) ([]byte, error)
+		SendAttachment(ctx context.Context, token string, req request.AttachmentRequest) ([]byte, error)
+		Subscribe(ctx context.Context, token string, onConnect library.SSEConnectHandler, onEvent library.SSEEventHandler) error
 	}
 
 	SalesforceOutboundImpl struct {
@@ -58,13 +75,7 @@ func (s *SalesforceOutboundImpl) GenerateToken(ctx context.Context, req request.
 		return nil, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	err = resp.Body.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	return body, nil
+	return readResponse(resp)
 }
 
 func (s *SalesforceOutboundImpl) CreateConversation(ctx context.Context, token string, req request.CreateConversationRequest) ([]byte, error) {
@@ -75,6 +86,7 @@ func (s *SalesforceOutboundImpl) CreateConversation(ctx context.Context, token s
 		"Content-Type":  "application/json",
 	}
 
+	req.CallbackUrl = ""
 	payload, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
@@ -90,16 +102,133 @@ func (s *SalesforceOutboundImpl) CreateConversation(ctx context.Context, token s
 		return nil, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	err = resp.Body.Close()
+	return readResponse(resp)
+}
+
+func (s *SalesforceOutboundImpl) SendMessage(ctx context.Context, token string, req request.SendMessageRequest) ([]byte, error) {
+	url := s.salesforceConfig.Host + fmt.Sprintf(sendMessagePath, req.ConversationId)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"esDeveloperName": req.EsDeveloperName,
+		"message": map[string]interface{}{
+			"id":          req.MessageId,
+			"messageType": "StaticContentMessage",
+			"staticContent": map[string]string{
+				"formatType": "Text",
+				"text":       req.Text,
+			},
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return body, nil
+	resp, err := s.httpClient.Post(ctx, request.HTTPRequest{
+		Path:    url,
+		Headers: s.headers(token, "application/json"),
+		Body:    bytes.NewReader(payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readResponse(resp)
+}
+
+func (s *SalesforceOutboundImpl) CloseConversation(ctx context.Context, token string, req request.CloseConversationRequest) ([]byte, error) {
+	url := s.salesforceConfig.Host + fmt.Sprintf(conversationPath, req.ConversationId)
+
+	resp, err := s.httpClient.Delete(ctx, request.HTTPRequest{
+		Path:    url,
+		Headers: s.headers(token, ""),
+		Queries: map[string]string{"esDeveloperName": req.EsDeveloperName},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readResponse(resp)
+}
+
+func (s *SalesforceOutboundImpl) SendTyping(ctx context.Context, token string, req request.TypingRequest) ([]byte, error) {
+	url := s.salesforceConfig.Host + fmt.Sprintf(conversationEntryPath, req.ConversationId)
+
+	entryType := "TypingStoppedIndicator"
+	if req.Started {
+		entryType = "TypingStartedIndicator"
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"id":        req.EntryId,
+		"entryType": entryType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Post(ctx, request.HTTPRequest{
+		Path:    url,
+		Headers: s.headers(token, "application/json"),
+		Body:    bytes.NewReader(payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readResponse(resp)
+}
+
+func (s *SalesforceOutboundImpl) SendAttachment(ctx context.Context, token string, req request.AttachmentRequest) ([]byte, error) {
+	url := s.salesforceConfig.Host + fmt.Sprintf(conversationFilePath, req.ConversationId)
+
+	messageEntry, err := json.Marshal(map[string]interface{}{
+		"esDeveloperName": req.EsDeveloperName,
+		"message": map[string]string{
+			"id":     req.MessageId,
+			"fileId": req.MessageId,
+			"text":   req.Text,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("messageEntry", string(messageEntry)); err != nil {
+		return nil, err
+	}
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="fileData"; filename=%q`, req.FileName)},
+		"Content-Type":        {req.ContentType},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := part.Write(req.Data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Post(ctx, request.HTTPRequest{
+		Path:    url,
+		Headers: s.headers(token, writer.FormDataContentType()),
+		Body:    &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readResponse(resp)
 }
 
-func (s *SalesforceOutboundImpl) Subscribe(ctx context.Context, token string) error {
+func (s *SalesforceOutboundImpl) Subscribe(ctx context.Context, token string, onConnect library.SSEConnectHandler, onEvent library.SSEEventHandler) error {
 	url := s.salesforceConfig.Host + ssePath
 
 	headers := map[string]string{
@@ -107,10 +236,42 @@ func (s *SalesforceOutboundImpl) Subscribe(ctx context.Context, token string) er
 		"X-Org-Id":      s.salesforceConfig.OrgId,
 	}
 
-	sseClient := library.NewSSEClient(url, headers)
+	sseClient := library.NewSSEClient(url, headers, onConnect, onEvent)
 	if err := sseClient.Start(ctx); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (s *SalesforceOutboundImpl) headers(token string, contentType string) map[string]string {
+	headers := map[string]string{
+		"Authorization": "Bearer " + token,
+	}
+
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+
+	return headers
+}
+
+func (e *SalesforceError) Error() string {
+	return fmt.Sprintf("salesforce responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+func readResponse(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if closeErr := resp.Body.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &SalesforceError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	return body, nil
+}
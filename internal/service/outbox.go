@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"log/slog"
+	"os"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"time"
+)
+
+type (
+	OutboxService interface {
+		Enabled() bool
+		Enqueue(ctx context.Context, msg *library.Message) error
+		Relay(ctx context.Context)
+	}
+
+	OutboxServiceImpl struct {
+		outboxConfig     configs.OutboxConfig
+		queueProducer    library.QueueProducer
+		outboxRepository repository.OutboxRepository
+		owner            string
+		purgedAt         time.Time
+	}
+)
+
+func NewOutboxService(outboxConfig configs.OutboxConfig, queueProducer library.QueueProducer, outboxRepository repository.OutboxRepository) OutboxService {
+	hostname, _ := os.Hostname()
+
+	return &OutboxServiceImpl{
+		outboxConfig:     outboxConfig,
+		queueProducer:    queueProducer,
+		outboxRepository: outboxRepository,
+		owner:            fmt.Sprintf("%s-%s", hostname, newId()),
+	}
+}
+
+func (s *OutboxServiceImpl) Enabled() bool {
+	return s.outboxConfig.Enabled
+}
+
+func (s *OutboxServiceImpl) Enqueue(ctx context.Context, msg *library.Message) error {
+	data := model.OutboxMessage{
+		Id:        bson.NewObjectID(),
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Status:    model.OutboxStatusPending,
+		Partition: -1,
+		Offset:    -1,
+		CreatedAt: time.Now(),
+	}
+
+	if msg.ManualPartition {
+		data.Partition = msg.Partition
+		data.Manual = true
+	}
+
+	for _, header := range msg.Headers {
+		data.Headers = append(data.Headers, model.OutboxHeader{Key: header.Key, Value: header.Value})
+	}
+
+	if _, err := s.outboxRepository.Upsert(ctx, data); err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+
+	return nil
+}
+
+func (s *OutboxServiceImpl) Relay(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.outboxConfig.PollInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		leaseExpiresAt, leader, err := s.acquireLease(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to acquire outbox lease", slog.Any("error", err))
+		} else if leader {
+			if err := s.relayBatch(ctx, leaseExpiresAt); err != nil {
+				slog.ErrorContext(ctx, "Failed to relay outbox messages", slog.Any("error", err))
+			}
+
+			if time.Since(s.purgedAt) >= time.Duration(s.outboxConfig.PurgeInterval)*time.Millisecond {
+				s.purgeSent(ctx)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OutboxServiceImpl) acquireLease(ctx context.Context) (time.Time, bool, error) {
+	now := time.Now()
+
+	leader, err := s.outboxRepository.AcquireLease(ctx, s.owner, time.Duration(s.outboxConfig.LeaseTimeout)*time.Millisecond)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return now.Add(time.Duration(s.outboxConfig.LeaseTimeout) * time.Millisecond), leader, nil
+}
+
+func (s *OutboxServiceImpl) purgeSent(ctx context.Context) {
+	s.purgedAt = time.Now()
+	before := s.purgedAt.Add(-time.Duration(s.outboxConfig.SentRetention) * time.Millisecond)
+
+	deleted, err := s.outboxRepository.DeleteSent(ctx, before)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to purge sent outbox messages", slog.Any("error", err))
+		return
+	}
+
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Purged sent outbox messages", slog.Int64("count", deleted))
+	}
+}
+
+func (s *OutboxServiceImpl) relayBatch(ctx context.Context, leaseExpiresAt time.Time) error {
+	messages, err := s.outboxRepository.FindPending(ctx, s.outboxConfig.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to find pending outbox messages: %w", err)
+	}
+
+	renewBefore := time.Duration(s.outboxConfig.LeaseTimeout) * time.Millisecond / 2
+	for _, message := range messages {
+		if time.Until(leaseExpiresAt) < renewBefore {
+			expiresAt, leader, err := s.acquireLease(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to renew outbox lease: %w", err)
+			}
+			if !leader {
+				slog.WarnContext(ctx, "Outbox lease lost, stopping batch")
+				return nil
+			}
+			leaseExpiresAt = expiresAt
+		}
+
+		msg := &library.Message{
+			Topic:           message.Topic,
+			Partition:       message.Partition,
+			ManualPartition: message.Manual,
+			Key:             message.Key,
+			Value:           message.Value,
+		}
+		for _, header := range message.Headers {
+			msg.Headers = append(msg.Headers, library.MessageHeader{Key: header.Key, Value: header.Value})
+		}
+
+		message.Attempts++
+		partition, offset, err := s.queueProducer.Produce(ctx, msg)
+		if err != nil {
+			message.LastError = err.Error()
+			if _, updateErr := s.outboxRepository.Upsert(ctx, message); updateErr != nil {
+				slog.ErrorContext(ctx, "Failed to record outbox attempt", slog.String("id", message.Id.Hex()), slog.Any("error", updateErr))
+			}
+
+			return fmt.Errorf("failed to produce outbox message %s: %w", message.Id.Hex(), err)
+		}
+
+		sentAt := time.Now()
+		message.Status = model.OutboxStatusSent
+		message.LastError = ""
+		message.Partition = partition
+		message.Offset = offset
+		message.SentAt = &sentAt
+		if _, err := s.outboxRepository.Upsert(ctx, message); err != nil {
+			return fmt.Errorf("failed to mark outbox message %s sent: %w", message.Id.Hex(), err)
+		}
+
+		slog.InfoContext(ctx, "Outbox message relayed",
+			slog.String("id", message.Id.Hex()),
+			slog.String("topic", message.Topic),
+			slog.Int("partition", int(partition)),
+			slog.Int64("offset", offset),
+		)
+	}
+
+	return nil
+}
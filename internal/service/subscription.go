@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/service/outbound"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	SubscriptionService interface {
+		Subscribe(ctx context.Context, partition int)
+		Unsubscribe(ctx context.Context, partitions ...int)
+		Resubscribe(ctx context.Context, partition int)
+		Status() []SubscriptionStatus
+	}
+
+	SubscriptionStatus struct {
+		Partition   int
+		Status      string
+		Error       string
+		ConnectedAt time.Time
+		LastEventAt time.Time
+	}
+
+	SubscriptionServiceImpl struct {
+		mu                  sync.Mutex
+		subscriptions       map[int]*subscription
+		owner               string
+		tokenCache          TokenCache
+		conversationService ConversationService
+		salesforceOutbound  outbound.SalesforceOutbound
+		sseStatusRepository repository.SseStatusRepository
+	}
+
+	subscription struct {
+		parent context.Context
+		cancel context.CancelFunc
+		status SubscriptionStatus
+	}
+)
+
+func NewSubscriptionService(tokenCache TokenCache, conversationService ConversationService, salesforceOutbound outbound.SalesforceOutbound, sseStatusRepository repository.SseStatusRepository, lifecycle library.Lifecycle) SubscriptionService {
+	hostname, _ := os.Hostname()
+
+	s := &SubscriptionServiceImpl{
+		subscriptions:       map[int]*subscription{},
+		owner:               hostname,
+		tokenCache:          tokenCache,
+		conversationService: conversationService,
+		salesforceOutbound:  salesforceOutbound,
+		sseStatusRepository: sseStatusRepository,
+	}
+
+	lifecycle.Append(library.LifecycleHook{
+		Name:  "sse subscriptions",
+		Order: library.LifecycleOrderService,
+		OnStop: func(ctx context.Context) error {
+			s.Unsubscribe(ctx, s.partitions()...)
+			return nil
+		},
+	})
+
+	return s
+}
+
+func (s *SubscriptionServiceImpl) Subscribe(ctx context.Context, partition int) {
+	subscriptionCtx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	if previous, ok := s.subscriptions[partition]; ok {
+		previous.cancel()
+	}
+	current := &subscription{
+		parent: ctx,
+		cancel: cancel,
+		status: SubscriptionStatus{Partition: partition, Status: model.SseStatusConnecting},
+	}
+	s.subscriptions[partition] = current
+	s.mu.Unlock()
+
+	go s.run(subscriptionCtx, partition, current)
+}
+
+func (s *SubscriptionServiceImpl) Unsubscribe(ctx context.Context, partitions ...int) {
+	for _, partition := range partitions {
+		s.mu.Lock()
+		current, ok := s.subscriptions[partition]
+		delete(s.subscriptions, partition)
+		s.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		current.cancel()
+		s.recordStatus(ctx, partition, current, model.SseStatusRevoked, nil)
+	}
+}
+
+func (s *SubscriptionServiceImpl) Resubscribe(ctx context.Context, partition int) {
+	s.mu.Lock()
+	parent := ctx
+	if current, ok := s.subscriptions[partition]; ok {
+		parent = current.parent
+	}
+	s.mu.Unlock()
+
+	slog.InfoContext(ctx, "SSE Resubscribing", slog.Int("partition", partition))
+
+	s.tokenCache.Invalidate(partition)
+	s.Subscribe(parent, partition)
+}
+
+func (s *SubscriptionServiceImpl) Status() []SubscriptionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]SubscriptionStatus, 0, len(s.subscriptions))
+	for _, current := range s.subscriptions {
+		statuses = append(statuses, current.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Partition < statuses[j].Partition
+	})
+
+	return statuses
+}
+
+func (s *SubscriptionServiceImpl) partitions() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	partitions := make([]int, 0, len(s.subscriptions))
+	for partition := range s.subscriptions {
+		partitions = append(partitions, partition)
+	}
+
+	return partitions
+}
+
+func (s *SubscriptionServiceImpl) run(ctx context.Context, partition int, current *subscription) {
+	token, err := s.tokenCache.Get(ctx, partition)
+	if err != nil {
+		s.recordStatus(ctx, partition, current, model.SseStatusError, err)
+		return
+	}
+
+	onEvent := func(eventType string, data []byte) {
+		s.mu.Lock()
+		current.status.LastEventAt = time.Now()
+		s.mu.Unlock()
+
+		s.conversationService.HandleEvent(ctx, eventType, data)
+	}
+
+	onConnect := func() {
+		s.recordStatus(ctx, partition, current, model.SseStatusConnected, nil)
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	s.recordStatus(ctx, partition, current, model.SseStatusConnecting, nil)
+
+	err = s.salesforceOutbound.Subscribe(ctx, token, onConnect, onEvent)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		s.recordStatus(ctx, partition, current, model.SseStatusError, err)
+		return
+	}
+
+	s.recordStatus(ctx, partition, current, model.SseStatusDisconnected, nil)
+}
+
+func (s *SubscriptionServiceImpl) recordStatus(ctx context.Context, partition int, current *subscription, status string, cause error) {
+	data := model.SseStatus{
+		Partition: partition,
+		Status:    status,
+		Owner:     s.owner,
+		UpdatedAt: time.Now(),
+	}
+	if cause != nil {
+		data.Error = cause.Error()
+	}
+
+	s.mu.Lock()
+	current.status.Status = status
+	current.status.Error = data.Error
+	if status == model.SseStatusConnected {
+		current.status.ConnectedAt = data.UpdatedAt
+	}
+	s.mu.Unlock()
+
+	if _, err := s.sseStatusRepository.Upsert(context.WithoutCancel(ctx), data); err != nil {
+		slog.ErrorContext(ctx, "Failed to record SSE status", slog.Int("partition", partition), slog.Any("error", err))
+	}
+}
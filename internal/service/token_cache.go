@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"sync"
+	"time"
+)
+
+const (
+	tokenWatchMinBackoff = time.Second
+	tokenWatchMaxBackoff = time.Minute
+)
+
+type (
+	TokenCache interface {
+		Get(ctx context.Context, partition int) (string, error)
+		Load(ctx context.Context, partitions []int)
+		Invalidate(partitions ...int)
+		InvalidateAll()
+		Watch(ctx context.Context)
+	}
+
+	TokenCacheImpl struct {
+		mu                            sync.RWMutex
+		tokens                        map[int]string
+		generation                    uint64
+		cacheConfig                   configs.CacheConfig
+		conversationMappingRepository repository.ConversationMappingRepository
+	}
+)
+
+func NewTokenCache(cacheConfig configs.CacheConfig, conversationMappingRepository repository.ConversationMappingRepository) TokenCache {
+	return &TokenCacheImpl{
+		tokens:                        map[int]string{},
+		cacheConfig:                   cacheConfig,
+		conversationMappingRepository: conversationMappingRepository,
+	}
+}
+
+func (t *TokenCacheImpl) Get(ctx context.Context, partition int) (string, error) {
+	t.mu.RLock()
+	token, ok := t.tokens[partition]
+	generation := t.generation
+	t.mu.RUnlock()
+
+	if ok {
+		return token, nil
+	}
+
+	conversationMapping, err := t.conversationMappingRepository.FindOneByPartition(ctx, partition)
+	if err != nil {
+		return "", fmt.Errorf("%w for partition %d: %w", ErrTokenNotFound, partition, err)
+	}
+	if conversationMapping == nil {
+		return "", fmt.Errorf("%w for partition %d", ErrTokenNotFound, partition)
+	}
+
+	t.mu.Lock()
+	if t.generation == generation {
+		t.tokens[partition] = conversationMapping.Token
+	}
+	t.mu.Unlock()
+
+	return conversationMapping.Token, nil
+}
+
+func (t *TokenCacheImpl) Load(ctx context.Context, partitions []int) {
+	for _, partition := range partitions {
+		if _, err := t.Get(ctx, partition); err != nil {
+			slog.ErrorContext(ctx, "Failed to load token", slog.Int("partition", partition), slog.Any("error", err))
+		}
+	}
+}
+
+func (t *TokenCacheImpl) Invalidate(partitions ...int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.generation++
+	for _, partition := range partitions {
+		delete(t.tokens, partition)
+	}
+}
+
+func (t *TokenCacheImpl) InvalidateAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.generation++
+	t.tokens = map[int]string{}
+}
+
+func (t *TokenCacheImpl) Watch(ctx context.Context) {
+	if !t.cacheConfig.TokenChangeStream {
+		return
+	}
+
+	backoff := tokenWatchMinBackoff
+	for {
+		started := time.Now()
+
+		err := t.conversationMappingRepository.Watch(ctx, func(conversationMapping *model.ConversationMapping) {
+			if conversationMapping == nil {
+				t.InvalidateAll()
+				return
+			}
+
+			t.mu.Lock()
+			t.generation++
+			t.tokens[conversationMapping.Partition] = conversationMapping.Token
+			t.mu.Unlock()
+
+			slog.InfoContext(ctx, "Token refreshed from change stream", slog.Int("partition", conversationMapping.Partition))
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		t.InvalidateAll()
+
+		if time.Since(started) > tokenWatchMaxBackoff {
+			backoff = tokenWatchMinBackoff
+		}
+
+		slog.ErrorContext(ctx, "Token change stream stopped, reconnecting", slog.Any("error", err), slog.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, tokenWatchMaxBackoff)
+	}
+}
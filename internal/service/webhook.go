@@ -0,0 +1,349 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"io"
+	"log/slog"
+	"net/url"
+	"salesforce-sse-worker/configs"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/model"
+	"salesforce-sse-worker/internal/repository"
+	"salesforce-sse-worker/internal/request"
+	"salesforce-sse-worker/internal/response"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type (
+	WebhookService interface {
+		ValidateCallbackUrl(callbackUrl string) error
+		RegisterCallback(ctx context.Context, conversationId string, callbackUrl string) error
+		Notify(ctx context.Context, conversationId string, event string, data interface{})
+		FindDeliveries(ctx context.Context, status string) ([]model.WebhookDelivery, error)
+		FindDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error)
+		Redeliver(ctx context.Context, id string) (*model.WebhookDelivery, error)
+	}
+
+	WebhookServiceImpl struct {
+		webhookConfig                  configs.WebhookConfig
+		httpClient                     library.HTTPClient
+		conversationCallbackRepository repository.ConversationCallbackRepository
+		webhookDeliveryRepository      repository.WebhookDeliveryRepository
+		wake                           chan struct{}
+	}
+)
+
+func NewWebhookService(webhookConfig configs.WebhookConfig, httpClient library.HTTPClient, conversationCallbackRepository repository.ConversationCallbackRepository, webhookDeliveryRepository repository.WebhookDeliveryRepository, lifecycle library.Lifecycle) WebhookService {
+	w := &WebhookServiceImpl{
+		webhookConfig:                  webhookConfig,
+		httpClient:                     httpClient,
+		conversationCallbackRepository: conversationCallbackRepository,
+		webhookDeliveryRepository:      webhookDeliveryRepository,
+		wake:                           make(chan struct{}, 1),
+	}
+
+	if webhookConfig.Enabled() {
+		lifecycle.AppendBackground("webhook relay", library.LifecycleOrderService, func(ctx context.Context) error {
+			w.relay(ctx)
+			return nil
+		})
+	}
+
+	return w
+}
+
+func (w *WebhookServiceImpl) ValidateCallbackUrl(callbackUrl string) error {
+	parsed, err := url.Parse(callbackUrl)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCallbackNotAllowed, err)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrCallbackNotAllowed, parsed.Scheme)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range w.webhookConfig.AllowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host %q", ErrCallbackNotAllowed, host)
+}
+
+func (w *WebhookServiceImpl) RegisterCallback(ctx context.Context, conversationId string, callbackUrl string) error {
+	if err := w.ValidateCallbackUrl(callbackUrl); err != nil {
+		return err
+	}
+
+	if _, err := w.conversationCallbackRepository.Upsert(ctx, model.ConversationCallback{
+		ConversationId: conversationId,
+		CallbackUrl:    callbackUrl,
+		CreatedAt:      time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to register callback: %w", err)
+	}
+
+	return nil
+}
+
+func (w *WebhookServiceImpl) Notify(ctx context.Context, conversationId string, event string, data interface{}) {
+	if !w.webhookConfig.Enabled() {
+		return
+	}
+
+	callback, err := w.conversationCallbackRepository.FindOneByConversationId(ctx, conversationId)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find callback", slog.String("conversationId", conversationId), slog.Any("error", err))
+		return
+	}
+
+	if callback == nil {
+		return
+	}
+
+	delivery, err := w.newDelivery(callback, event, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to build webhook delivery", slog.String("conversationId", conversationId), slog.Any("error", err))
+		return
+	}
+
+	if _, err := w.webhookDeliveryRepository.Upsert(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "Failed to persist webhook delivery", slog.String("conversationId", conversationId), slog.Any("error", err))
+		return
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *WebhookServiceImpl) FindDeliveries(ctx context.Context, status string) ([]model.WebhookDelivery, error) {
+	return w.webhookDeliveryRepository.FindAll(ctx, status)
+}
+
+func (w *WebhookServiceImpl) FindDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	objectId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid delivery id", ErrInvalidMessage)
+	}
+
+	return w.webhookDeliveryRepository.FindOneById(ctx, objectId)
+}
+
+func (w *WebhookServiceImpl) Redeliver(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	delivery, err := w.FindDelivery(ctx, id)
+	if delivery == nil || err != nil {
+		return nil, err
+	}
+
+	if err := w.attempt(ctx, delivery); err != nil {
+		delivery.Status = model.WebhookStatusFailed
+	}
+
+	if _, err := w.webhookDeliveryRepository.Upsert(ctx, *delivery); err != nil {
+		return nil, fmt.Errorf("failed to persist webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+func (w *WebhookServiceImpl) newDelivery(callback *model.ConversationCallback, event string, data interface{}) (model.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := model.WebhookDelivery{
+		Id:             bson.NewObjectID(),
+		ConversationId: callback.ConversationId,
+		Event:          event,
+		Url:            callback.CallbackUrl,
+		Status:         model.WebhookStatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		NextAttemptAt:  now,
+	}
+
+	payload := response.WebhookPayload{
+		Id:             delivery.Id.Hex(),
+		Event:          event,
+		ConversationId: callback.ConversationId,
+		Timestamp:      now,
+	}
+
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return delivery, err
+		}
+		payload.Data = encoded
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return delivery, err
+	}
+	delivery.Payload = string(body)
+
+	return delivery, nil
+}
+
+func (w *WebhookServiceImpl) relay(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(w.webhookConfig.RelayInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *WebhookServiceImpl) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		var wg sync.WaitGroup
+
+		claimed := 0
+		for ; claimed < w.webhookConfig.RelayBatchSize; claimed++ {
+			now := time.Now()
+			leaseUntil := now.Add(2 * time.Duration(w.webhookConfig.Timeout) * time.Millisecond)
+
+			delivery, err := w.webhookDeliveryRepository.ClaimDue(ctx, now, leaseUntil)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to claim webhook delivery", slog.Any("error", err))
+				break
+			}
+			if delivery == nil {
+				break
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.deliver(ctx, *delivery)
+			}()
+		}
+
+		wg.Wait()
+
+		if claimed < w.webhookConfig.RelayBatchSize {
+			return
+		}
+	}
+}
+
+func (w *WebhookServiceImpl) deliver(ctx context.Context, delivery model.WebhookDelivery) {
+	if err := w.attempt(ctx, &delivery); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case delivery.Status != model.WebhookStatusPending:
+		case delivery.Attempts >= w.webhookConfig.MaxAttempts:
+			delivery.Status = model.WebhookStatusFailed
+		default:
+			delivery.NextAttemptAt = time.Now().Add(w.backoff(delivery.Attempts))
+		}
+	}
+
+	if _, err := w.webhookDeliveryRepository.Upsert(context.WithoutCancel(ctx), delivery); err != nil {
+		slog.ErrorContext(ctx, "Failed to persist webhook delivery", slog.String("id", delivery.Id.Hex()), slog.Any("error", err))
+	}
+
+	if delivery.Status != model.WebhookStatusPending {
+		slog.InfoContext(ctx, "Webhook delivery finished",
+			slog.String("id", delivery.Id.Hex()),
+			slog.String("status", delivery.Status),
+			slog.Int("attempts", delivery.Attempts),
+		)
+	}
+}
+
+func (w *WebhookServiceImpl) backoff(attempts int) time.Duration {
+	backoff := time.Duration(w.webhookConfig.InitialBackoff) * time.Millisecond
+	maxBackoff := time.Duration(w.webhookConfig.MaxBackoff) * time.Millisecond
+
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxBackoff)
+}
+
+func (w *WebhookServiceImpl) attempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(w.webhookConfig.Timeout)*time.Millisecond)
+	defer cancel()
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	delivery.Attempts++
+	delivery.UpdatedAt = time.Now()
+
+	if err := w.ValidateCallbackUrl(delivery.Url); err != nil {
+		delivery.Status = model.WebhookStatusFailed
+		delivery.LastError = err.Error()
+		return err
+	}
+
+	resp, err := w.httpClient.Post(ctx, request.HTTPRequest{
+		Path: delivery.Url,
+		Headers: map[string]string{
+			"Content-Type":         "application/json",
+			webhookSignatureHeader: "sha256=" + w.sign(timestamp, delivery.Payload),
+			webhookTimestampHeader: timestamp,
+			webhookEventHeader:     delivery.Event,
+			webhookDeliveryHeader:  delivery.Id.Hex(),
+		},
+		Body: bytes.NewReader([]byte(delivery.Payload)),
+	})
+	if err != nil {
+		delivery.LastError = err.Error()
+		return err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	delivery.LastStatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		delivery.LastError = "unexpected status " + resp.Status
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	now := time.Now()
+	delivery.Status = model.WebhookStatusDelivered
+	delivery.LastError = ""
+	delivery.DeliveredAt = &now
+
+	return nil
+}
+
+func (w *WebhookServiceImpl) sign(timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(w.webhookConfig.Secret))
+	mac.Write([]byte(timestamp + "." + payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
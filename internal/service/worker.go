@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"salesforce-sse-worker/internal/library"
+	"salesforce-sse-worker/internal/response"
+	"time"
+)
+
+type (
+	WorkerService interface {
+		Status(ctx context.Context) response.WorkerStatusResponse
+		Pause(ctx context.Context, partition int) error
+		Resume(ctx context.Context, partition int) error
+	}
+
+	WorkerServiceImpl struct {
+		queueConsumer       library.QueueConsumer
+		subscriptionService SubscriptionService
+	}
+)
+
+func NewWorkerService(queueConsumer library.QueueConsumer, subscriptionService SubscriptionService) WorkerService {
+	return &WorkerServiceImpl{
+		queueConsumer:       queueConsumer,
+		subscriptionService: subscriptionService,
+	}
+}
+
+func (w *WorkerServiceImpl) Status(ctx context.Context) response.WorkerStatusResponse {
+	hostname, _ := os.Hostname()
+	consumerStatus := w.queueConsumer.Status()
+
+	subscriptions := map[int]SubscriptionStatus{}
+	for _, status := range w.subscriptionService.Status() {
+		subscriptions[status.Partition] = status
+	}
+
+	resp := response.WorkerStatusResponse{
+		Hostname:   hostname,
+		Generation: consumerStatus.Generation,
+		Partitions: make([]response.WorkerPartitionResponse, 0, len(consumerStatus.Partitions)),
+	}
+
+	for _, partition := range consumerStatus.Partitions {
+		partitionResponse := response.WorkerPartitionResponse{
+			Topic:      partition.Topic,
+			Partition:  partition.Partition,
+			InFlight:   partition.InFlight,
+			LastOffset: partition.LastOffset,
+			Paused:     partition.Paused,
+			SseStatus:  sseStatusUnknown,
+		}
+
+		if subscription, ok := subscriptions[int(partition.Partition)]; ok {
+			partitionResponse.SseStatus = subscription.Status
+			partitionResponse.SseError = subscription.Error
+			partitionResponse.SseConnectedAt = optionalTime(subscription.ConnectedAt)
+			partitionResponse.SseLastEventAt = optionalTime(subscription.LastEventAt)
+		}
+
+		resp.Partitions = append(resp.Partitions, partitionResponse)
+	}
+
+	return resp
+}
+
+func (w *WorkerServiceImpl) Pause(ctx context.Context, partition int) error {
+	claims := w.claims(partition)
+	if len(claims) == 0 {
+		return fmt.Errorf("%w: %d is not assigned to this worker", ErrPartitionNotFound, partition)
+	}
+
+	w.queueConsumer.Pause(claims)
+
+	return nil
+}
+
+func (w *WorkerServiceImpl) Resume(ctx context.Context, partition int) error {
+	claims := w.claims(partition)
+	if len(claims) == 0 {
+		return fmt.Errorf("%w: %d is not assigned to this worker", ErrPartitionNotFound, partition)
+	}
+
+	w.queueConsumer.Resume(claims)
+
+	return nil
+}
+
+func (w *WorkerServiceImpl) claims(partition int) map[string][]int32 {
+	claims := map[string][]int32{}
+	for _, partitionStatus := range w.queueConsumer.Status().Partitions {
+		if partitionStatus.Partition == int32(partition) {
+			claims[partitionStatus.Topic] = append(claims[partitionStatus.Topic], partitionStatus.Partition)
+		}
+	}
+
+	return claims
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}